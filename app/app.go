@@ -0,0 +1,164 @@
+// Package app runs the gosh command. Custom gosh binaries, built by
+// `gosh extract`, register their precompiled package symbols before calling
+// Main.
+package app
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	"github.com/Napolitain/gosh/internal/shell"
+	"github.com/Napolitain/gosh/internal/workspace"
+	"github.com/traefik/yaegi/interp"
+)
+
+// symbols holds the registered precompiled package symbols
+var symbols = interp.Exports{}
+
+// Register makes precompiled package symbols, as generated by `gosh extract`
+// or `yaegi extract`, importable from the session. It must be called before
+// Main.
+func Register(exports interp.Exports) {
+	for path, pkg := range exports {
+		symbols[path] = pkg
+	}
+}
+
+// Main parses the command line and runs gosh
+func Main() {
+	var opts shell.Options
+	flag.BoolVar(&opts.NoExportPrompt, "no-export-prompt", false, "do not offer to save the session as a CLI tool on exit")
+	flag.StringVar(&opts.Workspace, "workspace", "", "workspace directory (default: $GOSH_HOME, the .gosh directory of the project, or ~/.gosh)")
+	project := flag.Bool("project", false, "use a project workspace in ./.gosh, creating it if needed")
+	flag.BoolVar(&opts.NoModule, "no-module", false, "do not make the packages of the Go module in the current directory importable")
+	flag.BoolVar(&opts.NoRC, "no-rc", false, "do not evaluate the goshrc.go startup file of the workspace")
+	flag.StringVar(&opts.Session, "session", "", "name the session so it can be resumed by name")
+	flag.Func("set", "override a setting of config.json, as key=value (repeatable)", func(setting string) error {
+		opts.Settings = append(opts.Settings, setting)
+		return nil
+	})
+	flag.BoolVar(&opts.Resume, "resume", false, "continue a saved session: the given session ID or name, or the latest one")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: gosh [flags] [--resume [session]]\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "       gosh [flags] extract [--no-build] [--gosh-source=dir] <import-path>...\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if opts.Resume {
+		opts.ResumeID = flag.Arg(0)
+	}
+	if *project && opts.Workspace == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error locating project: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Workspace = workspace.ProjectPath(cwd)
+	}
+
+	if !opts.Resume && flag.Arg(0) == "extract" {
+		if err := extract(opts.Workspace, flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error extracting packages: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	opts.Symbols = symbols
+	sh, err := shell.NewWithOptions(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing shell: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := sh.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error running shell: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// extract handles `gosh extract`, which precompiles packages into a custom
+// gosh binary of the workspace
+func extract(workspaceDir string, args []string) error {
+	fs := flag.NewFlagSet("extract", flag.ContinueOnError)
+	var opts workspace.BundleOptions
+	fs.BoolVar(&opts.NoBuild, "no-build", false, "only generate the symbol tables, without building the custom gosh binary")
+	fs.StringVar(&opts.GoshSource, "gosh-source", "", "local gosh checkout to build the custom binary from")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gosh extract [--no-build] [--gosh-source=dir] <import-path>[@version]...\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no package to extract")
+	}
+
+	if opts.GoshSource == "" {
+		opts.GoshVersion, opts.GoshSource = goshBuild()
+	}
+
+	if workspaceDir == "" {
+		dir, err := workspace.DefaultPath()
+		if err != nil {
+			return err
+		}
+		workspaceDir = dir
+	}
+	ws, err := workspace.NewAt(workspaceDir)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+	defer ws.Close()
+
+	binary, err := ws.ExtractBundles(fs.Args(), opts)
+	if err != nil {
+		return err
+	}
+	for _, path := range fs.Args() {
+		fmt.Printf("✓ Extracted %s\n", path)
+	}
+	if binary != "" {
+		fmt.Printf("✓ Built %s, run it to use the precompiled packages\n", binary)
+	}
+	return nil
+}
+
+// goshBuild returns the gosh module version the running binary was built
+// from, or the local directory replacing it
+func goshBuild() (version, source string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", ""
+	}
+
+	module := &info.Main
+	if module.Path != workspace.GoshModule {
+		// Custom binaries depend on gosh instead of being gosh
+		module = nil
+		for _, dep := range info.Deps {
+			if dep.Path == workspace.GoshModule {
+				module = dep
+				break
+			}
+		}
+		if module == nil {
+			return "", ""
+		}
+	}
+
+	if module.Replace != nil {
+		if module.Replace.Version == "" {
+			return "", module.Replace.Path
+		}
+		module = module.Replace
+	}
+	if module.Version == "(devel)" {
+		return "", ""
+	}
+	return module.Version, ""
+}
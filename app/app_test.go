@@ -0,0 +1,19 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/traefik/yaegi/interp"
+)
+
+func TestRegister(t *testing.T) {
+	t.Cleanup(func() { symbols = interp.Exports{} })
+
+	Register(interp.Exports{"example.com/a/a": {"A": reflect.ValueOf(1)}})
+	Register(interp.Exports{"example.com/b/b": {"B": reflect.ValueOf(2)}})
+
+	if len(symbols) != 2 || symbols["example.com/b/b"]["B"].Int() != 2 {
+		t.Errorf("Unexpected registered symbols: %v", symbols)
+	}
+}
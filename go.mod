@@ -4,7 +4,6 @@ go 1.25
 
 require (
 	github.com/traefik/yaegi v0.16.1
+	golang.org/x/sys v0.37.0
 	golang.org/x/term v0.36.0
 )
-
-require golang.org/x/sys v0.37.0 // indirect
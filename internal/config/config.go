@@ -0,0 +1,264 @@
+// Package config loads the settings of the shell from the config.json file
+// of the workspace, environment variables and command line flags, in
+// increasing order of precedence
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the config file at the workspace root
+const FileName = "config.json"
+
+// envPrefix prefixes the environment variables overriding settings, such as
+// GOSH_PROMPT for prompt
+const envPrefix = "GOSH_"
+
+// DefaultSource is the source of settings left to their default value
+const DefaultSource = "default"
+
+// Submit keys
+const (
+	SubmitCtrlEnter = "ctrl-enter" // Ctrl+Enter submits, Enter adds a line
+	SubmitBlankLine = "blank-line" // Enter on an empty line submits
+)
+
+// Edit modes
+const (
+	EditRaw  = "raw"  // gosh reads keys in terminal raw mode
+	EditLine = "line" // the terminal edits lines, gosh reads them whole
+)
+
+// Themes
+const (
+	ThemeAuto  = "auto"  // colors on terminals, unless NO_COLOR is set
+	ThemeColor = "color" // always colors
+	ThemeNone  = "none"  // no colors
+)
+
+// Config holds the shell settings
+type Config struct {
+	Prompt             string   `json:"prompt"`
+	ContinuationPrompt string   `json:"continuation_prompt"`
+	SubmitKey          string   `json:"submit_key"`
+	EditMode           string   `json:"edit_mode"`
+	Theme              string   `json:"theme"`
+	HistorySize        int      `json:"history_size"` // 0 keeps every block
+	Timeout            Duration `json:"timeout"`      // 0 disables the timeout
+	Imports            []string `json:"imports"`      // packages imported into every interpreter
+	ExitPrompt         bool     `json:"exit_prompt"`  // offer to export a CLI tool on exit
+
+	sources map[string]string
+}
+
+// Duration is a time.Duration written like "30s" in the config file
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string such as "1m30s"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid duration %s, use a string like \"30s\"", data)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Keys lists the settings, in display order
+var Keys = []string{
+	"prompt", "continuation_prompt", "submit_key", "edit_mode", "theme",
+	"history_size", "timeout", "imports", "exit_prompt",
+}
+
+// Default returns the default settings
+func Default() *Config {
+	return &Config{
+		Prompt:             "gosh> ",
+		ContinuationPrompt: "...  ",
+		SubmitKey:          SubmitCtrlEnter,
+		EditMode:           EditRaw,
+		Theme:              ThemeAuto,
+		HistorySize:        1000,
+		Imports:            []string{"fmt"},
+		ExitPrompt:         true,
+		sources:            make(map[string]string),
+	}
+}
+
+// Load returns the default settings overridden by the config file at path,
+// if it exists
+func Load(path string) (*Config, error) {
+	c := Default()
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(content, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	for key := range keys {
+		c.sources[key] = path
+	}
+	return c, nil
+}
+
+// ApplyEnv overrides the settings given by GOSH_<KEY> environment variables,
+// such as GOSH_HISTORY_SIZE
+func (c *Config) ApplyEnv() error {
+	for _, key := range Keys {
+		name := EnvVar(key)
+		if value, ok := os.LookupEnv(name); ok {
+			if err := c.Set(key, value, "$"+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// EnvVar returns the environment variable overriding a setting
+func EnvVar(key string) string {
+	return envPrefix + strings.ToUpper(key)
+}
+
+// Set overrides a setting with a value given as text, recording where the
+// value comes from. Imports are comma-separated.
+func (c *Config) Set(key, value, source string) error {
+	updated := *c
+	updated.Imports = slices.Clone(c.Imports)
+
+	switch key {
+	case "prompt":
+		updated.Prompt = value
+	case "continuation_prompt":
+		updated.ContinuationPrompt = value
+	case "submit_key":
+		updated.SubmitKey = value
+	case "edit_mode":
+		updated.EditMode = value
+	case "theme":
+		updated.Theme = value
+	case "history_size":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q from %s", key, value, source)
+		}
+		updated.HistorySize = n
+	case "timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q from %s", key, value, source)
+		}
+		updated.Timeout = Duration(d)
+	case "imports":
+		updated.Imports = nil
+		for _, path := range strings.Split(value, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				updated.Imports = append(updated.Imports, path)
+			}
+		}
+	case "exit_prompt":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q from %s", key, value, source)
+		}
+		updated.ExitPrompt = b
+	default:
+		return fmt.Errorf("unknown setting %q from %s", key, source)
+	}
+
+	if err := updated.validate(); err != nil {
+		return fmt.Errorf("invalid setting from %s: %w", source, err)
+	}
+	*c = updated
+	c.sources[key] = source
+	return nil
+}
+
+// validate checks the settings that only accept some values
+func (c *Config) validate() error {
+	choices := []struct {
+		key, value string
+		allowed    []string
+	}{
+		{"submit_key", c.SubmitKey, []string{SubmitCtrlEnter, SubmitBlankLine}},
+		{"edit_mode", c.EditMode, []string{EditRaw, EditLine}},
+		{"theme", c.Theme, []string{ThemeAuto, ThemeColor, ThemeNone}},
+	}
+	for _, choice := range choices {
+		if !slices.Contains(choice.allowed, choice.value) {
+			return fmt.Errorf("%s must be one of %s, got %q", choice.key, strings.Join(choice.allowed, ", "), choice.value)
+		}
+	}
+	if c.HistorySize < 0 {
+		return fmt.Errorf("history_size cannot be negative")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative")
+	}
+	return nil
+}
+
+// Value returns a setting formatted for display
+func (c *Config) Value(key string) string {
+	switch key {
+	case "prompt":
+		return strconv.Quote(c.Prompt)
+	case "continuation_prompt":
+		return strconv.Quote(c.ContinuationPrompt)
+	case "submit_key":
+		return c.SubmitKey
+	case "edit_mode":
+		return c.EditMode
+	case "theme":
+		return c.Theme
+	case "history_size":
+		return strconv.Itoa(c.HistorySize)
+	case "timeout":
+		return time.Duration(c.Timeout).String()
+	case "imports":
+		return strings.Join(c.Imports, ",")
+	case "exit_prompt":
+		return strconv.FormatBool(c.ExitPrompt)
+	}
+	return ""
+}
+
+// Source returns where the value of a setting comes from: the config file
+// path, an environment variable, a flag or DefaultSource
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return DefaultSource
+}
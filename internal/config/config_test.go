@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatalf("Failed to load defaults: %v", err)
+	}
+	if c.Prompt != "gosh> " || !slices.Equal(c.Imports, []string{"fmt"}) || !c.ExitPrompt {
+		t.Errorf("Unexpected defaults: %+v", c)
+	}
+	if got := c.Source("prompt"); got != DefaultSource {
+		t.Errorf("Expected default source, got %s", got)
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	content := `{"prompt": "go> ", "timeout": "5s", "imports": ["fmt", "strings"], "exit_prompt": false}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	c, err = Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if c.Prompt != "go> " || time.Duration(c.Timeout) != 5*time.Second || len(c.Imports) != 2 || c.ExitPrompt {
+		t.Errorf("Unexpected config: %+v", c)
+	}
+	if c.Source("timeout") != path || c.Source("theme") != DefaultSource {
+		t.Errorf("Unexpected sources: timeout %s, theme %s", c.Source("timeout"), c.Source("theme"))
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]string{
+		`{"promt": "x"}`:          "unknown field",
+		`{"theme": "neon"}`:       "theme must be one of",
+		`{"timeout": 5}`:          "invalid duration",
+		`{"history_size": -1}`:    "cannot be negative",
+		`{"submit_key": "shift"}`: "submit_key must be one of",
+		`not json`:                "failed to parse",
+	}
+	for content, want := range tests {
+		path := filepath.Join(t.TempDir(), FileName)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", content, want, err)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	t.Setenv("GOSH_HISTORY_SIZE", "10")
+	t.Setenv("GOSH_IMPORTS", "fmt, strings,,os")
+
+	c := Default()
+	if err := c.ApplyEnv(); err != nil {
+		t.Fatalf("Failed to apply environment: %v", err)
+	}
+	if c.HistorySize != 10 || !slices.Equal(c.Imports, []string{"fmt", "strings", "os"}) {
+		t.Errorf("Unexpected config: %+v", c)
+	}
+	if got := c.Source("history_size"); got != "$GOSH_HISTORY_SIZE" {
+		t.Errorf("Unexpected source %s", got)
+	}
+
+	if err := c.Set("edit_mode", "line", "--set"); err != nil {
+		t.Fatalf("Failed to set edit mode: %v", err)
+	}
+	if c.EditMode != EditLine || c.Source("edit_mode") != "--set" {
+		t.Errorf("Unexpected edit mode %s from %s", c.EditMode, c.Source("edit_mode"))
+	}
+
+	for _, setting := range [][2]string{{"edit_mode", "vi"}, {"timeout", "soon"}, {"colour", "none"}, {"exit_prompt", "maybe"}} {
+		if err := c.Set(setting[0], setting[1], "--set"); err == nil {
+			t.Errorf("Expected an error setting %s=%s", setting[0], setting[1])
+		}
+	}
+	if c.EditMode != EditLine {
+		t.Errorf("A rejected setting should leave the config unchanged, got %s", c.EditMode)
+	}
+
+	t.Setenv("GOSH_TIMEOUT", "-1s")
+	if err := Default().ApplyEnv(); err == nil || !strings.Contains(err.Error(), "$GOSH_TIMEOUT") {
+		t.Errorf("Expected an error naming the variable, got %v", err)
+	}
+}
+
+func TestValue(t *testing.T) {
+	c := Default()
+	if err := c.Set("timeout", "90s", "--set"); err != nil {
+		t.Fatalf("Failed to set timeout: %v", err)
+	}
+	tests := map[string]string{
+		"prompt":       `"gosh> "`,
+		"timeout":      "1m30s",
+		"imports":      "fmt",
+		"history_size": "1000",
+		"exit_prompt":  "true",
+	}
+	for key, want := range tests {
+		if got := c.Value(key); got != want {
+			t.Errorf("Value(%s) = %s, want %s", key, got, want)
+		}
+	}
+}
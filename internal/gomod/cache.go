@@ -0,0 +1,130 @@
+package gomod
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CacheDir returns the module cache directory: $GOMODCACHE, or pkg/mod in
+// the first GOPATH entry, which defaults to ~/go
+func CacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	if gopath := filepath.SplitList(os.Getenv("GOPATH")); len(gopath) > 0 && gopath[0] != "" {
+		return filepath.Join(gopath[0], "pkg", "mod")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, "go", "pkg", "mod")
+	}
+	return ""
+}
+
+// escapePath escapes a module path or version for the module cache, which
+// replaces upper case letters with '!' followed by the lower case letter so
+// that case-insensitive file systems do not mix modules up
+func escapePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// cachedModuleDir returns the directory of a module version extracted in the
+// module cache, if present
+func cachedModuleDir(cacheDir string, v Version) (string, bool) {
+	if cacheDir == "" {
+		return "", false
+	}
+	dir := filepath.Join(cacheDir, filepath.FromSlash(escapePath(v.Path))+"@"+escapePath(v.Version))
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir, true
+	}
+	return "", false
+}
+
+// latestCached returns the newest version of a module present in the module
+// cache, comparing versions by their numbers. Like go get, releases are
+// preferred over pre-releases.
+func latestCached(cacheDir, modulePath string) (Version, string, bool) {
+	if cacheDir == "" {
+		return Version{}, "", false
+	}
+	escaped := filepath.Join(cacheDir, filepath.FromSlash(escapePath(modulePath)))
+	entries, err := os.ReadDir(filepath.Dir(escaped))
+	if err != nil {
+		return Version{}, "", false
+	}
+
+	prefix := filepath.Base(escaped) + "@"
+	var best Version
+	var bestDir string
+	for _, entry := range entries {
+		version, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || !entry.IsDir() {
+			continue
+		}
+		if bestDir == "" || newerCached(version, best.Version) {
+			best = Version{Path: modulePath, Version: version}
+			bestDir = filepath.Join(filepath.Dir(escaped), entry.Name())
+		}
+	}
+	return best, bestDir, bestDir != ""
+}
+
+// newerCached reports whether version a is preferred over b
+func newerCached(a, b string) bool {
+	if releaseA, releaseB := !isPrerelease(a), !isPrerelease(b); releaseA != releaseB {
+		return releaseA
+	}
+	return compareVersions(a, b) > 0
+}
+
+// isPrerelease reports whether a semantic version is a pre-release, such as
+// v1.0.0-rc.1 or a pseudo-version
+func isPrerelease(v string) bool {
+	core, _, _ := strings.Cut(v, "+")
+	return strings.Contains(core, "-")
+}
+
+// compareVersions orders semantic versions such as v1.10.2 by their numeric
+// components, ranking releases above pre-releases of the same version
+func compareVersions(a, b string) int {
+	coreA, preA, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	coreB, preB, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	partsA, partsB := strings.Split(coreA, "."), strings.Split(coreB, ".")
+
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var na, nb int
+		if i < len(partsA) {
+			na, _ = strconv.Atoi(strings.TrimSuffix(partsA[i], "+incompatible"))
+		}
+		if i < len(partsB) {
+			nb, _ = strconv.Atoi(strings.TrimSuffix(partsB[i], "+incompatible"))
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return strings.Compare(preA, preB)
+}
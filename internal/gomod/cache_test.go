@@ -0,0 +1,57 @@
+package gomod
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEscapePath(t *testing.T) {
+	tests := map[string]string{
+		"github.com/pkg/errors":      "github.com/pkg/errors",
+		"github.com/BurntSushi/toml": "github.com/!burnt!sushi/toml",
+		"v1.0.0-RC1":                 "v1.0.0-!r!c1",
+	}
+	for path, want := range tests {
+		if got := escapePath(path); got != want {
+			t.Errorf("escapePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.10.0", "v1.9.0", 1},
+		{"v1.2.0", "v2.0.0+incompatible", -1},
+		{"v1.0.0", "v1.0.0-rc.1", 1},
+		{"v1.0.0-alpha", "v1.0.0-beta", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLatestCached(t *testing.T) {
+	cache := writeModule(t, map[string]string{
+		"github.com/!burnt!sushi/toml@v1.2.0/go.mod":       "module github.com/BurntSushi/toml\n",
+		"github.com/!burnt!sushi/toml@v1.10.0/go.mod":      "module github.com/BurntSushi/toml\n",
+		"github.com/!burnt!sushi/toml@v1.11.0-rc.1/go.mod": "module github.com/BurntSushi/toml\n",
+	})
+
+	version, dir, ok := latestCached(cache, "github.com/BurntSushi/toml")
+	if !ok || version.Version != "v1.10.0" {
+		t.Fatalf("Expected v1.10.0, got %+v (%v)", version, ok)
+	}
+	if want := filepath.Join(cache, "github.com", "!burnt!sushi", "toml@v1.10.0"); dir != want {
+		t.Errorf("Expected %s, got %s", want, dir)
+	}
+
+	if _, _, ok := latestCached(cache, "github.com/BurntSushi/yaml"); ok {
+		t.Error("Expected no cached version of a missing module")
+	}
+}
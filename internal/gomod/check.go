@@ -0,0 +1,96 @@
+package gomod
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// UnsupportedError reports a package that the interpreter cannot load from
+// source
+type UnsupportedError struct {
+	ImportPath string // package using the unsupported feature
+	Importer   string // package of the session import that depends on it
+	Reason     string
+}
+
+// Error describes the unsupported package and how it was reached
+func (e *UnsupportedError) Error() string {
+	msg := fmt.Sprintf("package %s %s, which gosh cannot interpret from source", e.ImportPath, e.Reason)
+	if e.Importer != e.ImportPath {
+		msg += fmt.Sprintf(" (imported by %s)", e.Importer)
+	}
+	return msg
+}
+
+// FindUnsupported looks for a package using cgo or unsafe among the given
+// package and the packages it imports from fsys, and returns nil when there
+// is none
+func (fsys *SourceFS) FindUnsupported(importPath string) *UnsupportedError {
+	ctx := build.Default
+	ctx.CgoEnabled = true
+
+	seen := map[string]bool{importPath: true}
+	queue := []string{importPath}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+
+		dir, ok := fsys.PackageDir(pkg)
+		if !ok {
+			continue
+		}
+		imports := packageImports(&ctx, dir)
+		switch {
+		case imports["C"]:
+			return &UnsupportedError{ImportPath: pkg, Importer: importPath, Reason: "uses cgo"}
+		case imports["unsafe"]:
+			return &UnsupportedError{ImportPath: pkg, Importer: importPath, Reason: "uses unsafe"}
+		}
+
+		for imp := range imports {
+			if !seen[imp] {
+				seen[imp] = true
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return nil
+}
+
+// packageImports returns the packages imported by the non-test files of the
+// package in dir that match the build context
+func packageImports(ctx *build.Context, dir string) map[string]bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	imports := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if match, err := ctx.MatchFile(dir, name); err != nil || !match {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range file.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+				imports[path] = true
+			}
+		}
+	}
+	return imports
+}
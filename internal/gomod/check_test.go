@@ -0,0 +1,47 @@
+package gomod
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindUnsupported(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":            "module example.com/demo\n",
+		"pure/pure.go":      "package pure\n\nimport \"strings\"\n",
+		"wrapper/wrap.go":   "package wrapper\n\nimport \"example.com/demo/native\"\n",
+		"native/native.go":  "package native\n\n// #include <stdio.h>\nimport \"C\"\n",
+		"fast/fast.go":      "package fast\n\nimport \"unsafe\"\n",
+		"fast/fast_test.go": "package fast\n",
+		"tested/tested.go":  "package tested\n",
+		"tested/x_test.go":  "package tested\n\nimport \"unsafe\"\n",
+	})
+	fsys := NewSourceFS(&Module{Path: "example.com/demo", Dir: dir})
+
+	for _, pkg := range []string{"example.com/demo/pure", "example.com/demo/tested", "example.com/demo/missing"} {
+		if err := fsys.FindUnsupported(pkg); err != nil {
+			t.Errorf("%s: unexpected error: %v", pkg, err)
+		}
+	}
+
+	tests := []struct {
+		pkg, unsupported, reason string
+	}{
+		{"example.com/demo/native", "example.com/demo/native", "uses cgo"},
+		{"example.com/demo/wrapper", "example.com/demo/native", "uses cgo"},
+		{"example.com/demo/fast", "example.com/demo/fast", "uses unsafe"},
+	}
+	for _, tt := range tests {
+		err := fsys.FindUnsupported(tt.pkg)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.pkg)
+			continue
+		}
+		if err.ImportPath != tt.unsupported || err.Reason != tt.reason {
+			t.Errorf("%s: unexpected error: %+v", tt.pkg, err)
+		}
+		if tt.pkg != tt.unsupported && !strings.Contains(err.Error(), "imported by "+tt.pkg) {
+			t.Errorf("%s: expected the importer in %q", tt.pkg, err.Error())
+		}
+	}
+}
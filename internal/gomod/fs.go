@@ -0,0 +1,225 @@
+package gomod
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GoPath is the virtual GOPATH served by SourceFS. The interpreter looks for
+// the source of an import path p in GoPath/src/p.
+const GoPath = "/gosh-gopath"
+
+// SourceFS is a read-only file system mapping import paths to the directory
+// holding their source, used as the interpreter source file system. It
+// serves the packages of the main module and of its dependencies, taken from
+// its vendor directory or the module cache, and falls back to the newest
+// version in the module cache for modules the main module does not require.
+type SourceFS struct {
+	cacheDir string
+
+	mu       sync.Mutex
+	roots    map[string]string // import path prefix to directory
+	searched map[string]bool   // import paths already searched in the module cache
+}
+
+// NewSourceFS returns a file system serving the packages of the main module
+// and its dependencies. The main module may be nil outside a module.
+func NewSourceFS(main *Module) *SourceFS {
+	fsys := &SourceFS{
+		cacheDir: CacheDir(),
+		roots:    make(map[string]string),
+		searched: make(map[string]bool),
+	}
+	if main != nil {
+		fsys.addMainModule(main)
+	}
+	return fsys
+}
+
+// Add serves the packages under the import path prefix from dir
+func (fsys *SourceFS) Add(prefix, dir string) {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+	fsys.roots[prefix] = dir
+}
+
+// Roots returns the import path prefixes served, sorted
+func (fsys *SourceFS) Roots() []string {
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	roots := make([]string, 0, len(fsys.roots))
+	for prefix := range fsys.roots {
+		roots = append(roots, prefix)
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// PackageDir returns the directory holding the source of a package
+func (fsys *SourceFS) PackageDir(importPath string) (string, bool) {
+	dir, ok := fsys.resolve(path.Join(filepath.ToSlash(GoPath), "src", importPath))
+	if !ok {
+		return "", false
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return dir, true
+}
+
+// Open opens the file or directory at GoPath/src/<import path>/...
+func (fsys *SourceFS) Open(name string) (fs.File, error) {
+	dir, ok := fsys.resolve(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// addMainModule serves the main module and its requirements. A vendor
+// directory takes precedence over the module cache, like for go build.
+func (fsys *SourceFS) addMainModule(main *Module) {
+	fsys.roots[main.Path] = main.Dir
+
+	if vendored := vendoredModules(main.Dir); vendored != nil {
+		for _, modulePath := range vendored {
+			fsys.roots[modulePath] = filepath.Join(main.Dir, "vendor", filepath.FromSlash(modulePath))
+		}
+		return
+	}
+
+	var deps []*Module
+	for _, req := range main.requires {
+		target, replaced := main.replaces[req.Path]
+		if !replaced {
+			target = req
+		}
+
+		var dir string
+		switch {
+		case target.isLocal():
+			dir = target.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(main.Dir, dir)
+			}
+		default:
+			var ok bool
+			if dir, ok = cachedModuleDir(fsys.cacheDir, target); !ok {
+				continue
+			}
+		}
+
+		fsys.roots[req.Path] = dir
+		deps = append(deps, &Module{Path: req.Path, Dir: dir})
+	}
+	fsys.addRequirements(deps)
+}
+
+// addRequirements serves the requirements of the given modules, and of their
+// own requirements, unless another version of a module is already served
+func (fsys *SourceFS) addRequirements(queue []*Module) {
+	for len(queue) > 0 {
+		module := queue[0]
+		queue = queue[1:]
+
+		loaded, err := Load(module.Dir)
+		if err != nil {
+			continue
+		}
+		for _, req := range loaded.requires {
+			if _, ok := fsys.roots[req.Path]; ok {
+				continue
+			}
+			if dir, ok := cachedModuleDir(fsys.cacheDir, req); ok {
+				fsys.roots[req.Path] = dir
+				queue = append(queue, &Module{Path: req.Path, Dir: dir})
+			}
+		}
+	}
+}
+
+// vendoredModules lists the modules in the vendor directory of a module, or
+// returns nil when it has none
+func vendoredModules(dir string) []string {
+	f, err := os.Open(filepath.Join(dir, "vendor", "modules.txt"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	modules := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Module lines look like "# path version [=> replacement]"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && fields[0] == "#" {
+			modules = append(modules, fields[1])
+		}
+	}
+	return modules
+}
+
+// resolve maps a path of the virtual GOPATH to a path on disk, using the
+// longest import path prefix matching it
+func (fsys *SourceFS) resolve(name string) (string, bool) {
+	name = filepath.ToSlash(name)
+	rel, ok := strings.CutPrefix(name, path.Join(filepath.ToSlash(GoPath), "src")+"/")
+	if !ok {
+		return "", false
+	}
+
+	fsys.mu.Lock()
+	defer fsys.mu.Unlock()
+
+	prefix, ok := fsys.longestRoot(rel)
+	if !ok {
+		if prefix, ok = fsys.searchCache(rel); !ok {
+			return "", false
+		}
+	}
+	return filepath.Join(fsys.roots[prefix], filepath.FromSlash(strings.TrimPrefix(rel, prefix))), true
+}
+
+// longestRoot returns the longest served import path prefix of rel
+func (fsys *SourceFS) longestRoot(rel string) (string, bool) {
+	best := ""
+	for prefix := range fsys.roots {
+		if (rel == prefix || strings.HasPrefix(rel, prefix+"/")) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	return best, best != ""
+}
+
+// searchCache looks for the module providing rel in the module cache and
+// serves its newest version along with its requirements
+func (fsys *SourceFS) searchCache(rel string) (string, bool) {
+	elems := strings.Split(rel, "/")
+	// Vendor directories are looked up by the interpreter before the GOPATH,
+	// and module paths start with a domain name
+	if fsys.searched[rel] || !strings.Contains(elems[0], ".") || strings.Contains("/"+rel+"/", "/vendor/") {
+		return "", false
+	}
+	fsys.searched[rel] = true
+
+	for n := len(elems); n > 0; n-- {
+		modulePath := strings.Join(elems[:n], "/")
+		if _, dir, ok := latestCached(fsys.cacheDir, modulePath); ok {
+			fsys.roots[modulePath] = dir
+			fsys.addRequirements([]*Module{{Path: modulePath, Dir: dir}})
+			return modulePath, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,121 @@
+package gomod
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestSourceFS(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/demo\n",
+		"greet/greet.go": "package greet\n",
+	})
+	nested := writeModule(t, map[string]string{
+		"go.mod":       "module example.com/demo/tools\n",
+		"lint/lint.go": "package lint\n",
+	})
+
+	fsys := NewSourceFS(&Module{Path: "example.com/demo", Dir: dir})
+	fsys.Add("example.com/demo/tools", nested)
+
+	src := filepath.Join(GoPath, "src")
+	for _, name := range []string{
+		filepath.Join(src, "example.com", "demo", "greet", "greet.go"),
+		filepath.Join(src, "example.com", "demo", "tools", "lint", "lint.go"),
+	} {
+		if _, err := fs.Stat(fsys, name); err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, filepath.Join(src, "example.com", "demo", "greet"))
+	if err != nil || len(entries) != 1 || entries[0].Name() != "greet.go" {
+		t.Errorf("Unexpected package directory entries: %v (%v)", entries, err)
+	}
+
+	for _, name := range []string{
+		filepath.Join(src, "example.com", "other"),
+		filepath.Join(src, "example.com", "demonstration"),
+		filepath.Join(src, "vendor", "example.com", "demo"),
+		filepath.Join(dir, "go.mod"),
+	} {
+		if _, err := fs.Stat(fsys, name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected %s not to exist, got %v", name, err)
+		}
+	}
+}
+
+func TestSourceFSModuleCache(t *testing.T) {
+	cache := writeModule(t, map[string]string{
+		"example.com/lib@v1.0.0/go.mod":        "module example.com/lib\n\nrequire example.com/util v0.1.0\n",
+		"example.com/lib@v1.0.0/lib.go":        "package lib\n",
+		"example.com/lib@v1.2.0/go.mod":        "module example.com/lib\n",
+		"example.com/lib@v1.2.0/lib.go":        "package lib\n",
+		"example.com/util@v0.1.0/util.go":      "package util\n",
+		"example.com/!upper/pkg@v0.3.0/pkg.go": "package pkg\n",
+	})
+	t.Setenv("GOMODCACHE", cache)
+
+	local := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/local\n",
+		"local/local.go": "package local\n",
+	})
+	main := writeModule(t, map[string]string{
+		"go.mod": "module example.com/demo\n\nrequire (\n\texample.com/lib v1.0.0\n\texample.com/local v0.0.0\n)\n\n" +
+			"replace example.com/local => " + local + "\n",
+	})
+
+	module, err := Load(main)
+	if err != nil {
+		t.Fatalf("Failed to load module: %v", err)
+	}
+	fsys := NewSourceFS(module)
+
+	want := map[string]string{
+		"example.com/lib":         filepath.Join(cache, "example.com", "lib@v1.0.0"),
+		"example.com/util":        filepath.Join(cache, "example.com", "util@v0.1.0"),
+		"example.com/local/local": filepath.Join(local, "local"),
+	}
+	for pkg, wantDir := range want {
+		if dir, ok := fsys.PackageDir(pkg); !ok || dir != wantDir {
+			t.Errorf("%s: expected %s, got %s (%v)", pkg, wantDir, dir, ok)
+		}
+	}
+
+	// Outside a module, the newest cached version is used
+	fsys = NewSourceFS(nil)
+	want = map[string]string{
+		"example.com/lib":       filepath.Join(cache, "example.com", "lib@v1.2.0"),
+		"example.com/Upper/pkg": filepath.Join(cache, "example.com", "!upper", "pkg@v0.3.0"),
+	}
+	for pkg, wantDir := range want {
+		if dir, ok := fsys.PackageDir(pkg); !ok || dir != wantDir {
+			t.Errorf("%s: expected %s, got %s (%v)", pkg, wantDir, dir, ok)
+		}
+	}
+	if _, ok := fsys.PackageDir("example.com/missing"); ok {
+		t.Error("Expected a missing module not to be found")
+	}
+}
+
+func TestSourceFSVendor(t *testing.T) {
+	t.Setenv("GOMODCACHE", t.TempDir())
+	dir := writeModule(t, map[string]string{
+		"go.mod":                        "module example.com/demo\n\nrequire example.com/lib v1.0.0\n",
+		"vendor/modules.txt":            "# example.com/lib v1.0.0\n## explicit\nexample.com/lib\n",
+		"vendor/example.com/lib/lib.go": "package lib\n",
+	})
+
+	module, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Failed to load module: %v", err)
+	}
+	fsys := NewSourceFS(module)
+
+	want := filepath.Join(dir, "vendor", "example.com", "lib")
+	if got, ok := fsys.PackageDir("example.com/lib"); !ok || got != want {
+		t.Errorf("Expected %s, got %s (%v)", want, got, ok)
+	}
+}
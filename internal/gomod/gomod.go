@@ -0,0 +1,150 @@
+// Package gomod locates Go modules on disk and exposes their source to the
+// interpreter as a virtual GOPATH, so sessions can import their packages
+package gomod
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Module is a Go module on disk
+type Module struct {
+	Path string // module path declared in go.mod
+	Dir  string // directory holding go.mod
+
+	requires []Version          // modules required by go.mod
+	replaces map[string]Version // replacements by module path
+}
+
+// Version identifies a module version, or a directory for a module replaced
+// by a local path
+type Version struct {
+	Path    string
+	Version string
+}
+
+// isLocal reports whether the version is a local directory replacement
+func (v Version) isLocal() bool {
+	return v.Version == "" && (strings.HasPrefix(v.Path, "./") || strings.HasPrefix(v.Path, "../") || filepath.IsAbs(v.Path))
+}
+
+// Find returns the module containing dir, looking for a go.mod in dir and
+// its parents. It returns nil without error when dir is not in a module.
+func Find(dir string) (*Module, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	for {
+		module, err := Load(dir)
+		if err == nil {
+			return module, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the module whose go.mod is in dir
+func Load(dir string) (*Module, error) {
+	goModPath := filepath.Join(dir, "go.mod")
+	content, err := os.ReadFile(goModPath)
+	if err != nil {
+		return nil, err
+	}
+
+	module := parseModFile(content)
+	if module.Path == "" {
+		return nil, fmt.Errorf("no module directive in %s", goModPath)
+	}
+	module.Dir = dir
+	return module, nil
+}
+
+// modulePath returns the path declared by the module directive of a go.mod
+func modulePath(content []byte) string {
+	return parseModFile(content).Path
+}
+
+// parseModFile extracts the module path, requirements and replacements of a
+// go.mod. Other directives are ignored.
+func parseModFile(content []byte) *Module {
+	module := &Module{replaces: make(map[string]Version)}
+
+	block := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			module.addDirective(block, fields)
+			continue
+		}
+
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		module.addDirective(fields[0], fields[1:])
+	}
+	return module
+}
+
+// addDirective records a go.mod directive with its arguments
+func (m *Module) addDirective(verb string, args []string) {
+	for i, arg := range args {
+		if unquoted, err := strconv.Unquote(arg); err == nil {
+			args[i] = unquoted
+		}
+	}
+
+	switch verb {
+	case "module":
+		if len(args) == 1 && m.Path == "" {
+			m.Path = args[0]
+		}
+	case "require":
+		if len(args) == 2 {
+			m.requires = append(m.requires, Version{Path: args[0], Version: args[1]})
+		}
+	case "replace":
+		arrow := -1
+		for i, arg := range args {
+			if arg == "=>" {
+				arrow = i
+			}
+		}
+		if arrow < 1 || arrow+1 >= len(args) {
+			return
+		}
+		target := Version{Path: args[arrow+1]}
+		if arrow+2 < len(args) {
+			target.Version = args[arrow+2]
+		}
+		m.replaces[args[0]] = target
+	}
+}
@@ -0,0 +1,58 @@
+package gomod
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeModule creates a module with the given files in a temporary directory
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestFind(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/demo\n\ngo 1.25\n",
+		"greet/greet.go": "package greet\n",
+	})
+
+	module, err := Find(filepath.Join(dir, "greet"))
+	if err != nil {
+		t.Fatalf("Failed to find module: %v", err)
+	}
+	if module == nil || module.Path != "example.com/demo" || module.Dir != dir {
+		t.Errorf("Unexpected module: %+v", module)
+	}
+
+	module, err = Find(t.TempDir())
+	if err != nil || module != nil {
+		t.Errorf("Expected no module outside a module, got %+v (%v)", module, err)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	tests := map[string]string{
+		"module example.com/demo\n":                            "example.com/demo",
+		"// comment\nmodule \"example.com/quoted\"\n":          "example.com/quoted",
+		"module example.com/commented // main module\ngo 1.25": "example.com/commented",
+		"modules example.com/wrong\n":                          "",
+		"go 1.25\n":                                            "",
+	}
+	for content, want := range tests {
+		if got := modulePath([]byte(content)); got != want {
+			t.Errorf("modulePath(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
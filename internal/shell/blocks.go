@@ -0,0 +1,269 @@
+package shell
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Napolitain/gosh/internal/workspace"
+	"github.com/traefik/yaegi/interp"
+)
+
+// consoleWriter forwards the interpreter output to the terminal, unless muted
+// while replaying blocks whose output was already shown
+type consoleWriter struct {
+	muted bool
+}
+
+// Write writes p to standard output unless the writer is muted
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	if c.muted {
+		return len(p), nil
+	}
+	return os.Stdout.Write(p)
+}
+
+// replay evaluates blocks in a new interpreter, without showing their output
+// again, and returns it along with the output of each block. Since every
+// statement runs again, the ones with side effects are listed afterwards.
+func (s *Shell) replay(blocks []string) (*interp.Interpreter, []string, error) {
+	i, err := s.newInterpreter()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.console.muted = true
+	outputs := make([]string, len(blocks))
+	for n, block := range blocks {
+		s.output.Reset()
+		if _, err := i.Eval(block); err != nil {
+			s.console.muted = false
+			printReplayedEffects(blocks[:n+1])
+			return nil, nil, fmt.Errorf("block %d: %w", n+1, err)
+		}
+		outputs[n] = s.output.String()
+	}
+	s.console.muted = false
+	printReplayedEffects(blocks)
+	return i, outputs, nil
+}
+
+// replayedEffects returns the statements with side effects of blocks, as
+// found by the reload plan, each with the number of its block
+func replayedEffects(blocks []string) []string {
+	var effects []string
+	for n, block := range workspace.PlanReload(blocks) {
+		for _, step := range block.Steps {
+			if step.SideEffects {
+				effects = append(effects, fmt.Sprintf("block %d: %s", n+1, firstLine(step.Code)))
+			}
+		}
+	}
+	return effects
+}
+
+// printReplayedEffects lists the statements with side effects that replaying
+// blocks ran again
+func printReplayedEffects(blocks []string) {
+	effects := replayedEffects(blocks)
+	if len(effects) == 0 {
+		return
+	}
+	fmt.Printf("Re-ran %d statements with side effects:\n", len(effects))
+	for _, effect := range effects {
+		fmt.Printf("  %s\n", effect)
+	}
+}
+
+// setBlocks replays blocks and, when they all evaluate, makes them the blocks
+// of the session. The session is left untouched otherwise.
+func (s *Shell) setBlocks(blocks []string) error {
+	i, outputs, err := s.replay(blocks)
+	if err != nil {
+		return err
+	}
+	if err := s.workspace.SetCodeBlocks(blocks, outputs); err != nil {
+		return err
+	}
+	s.interpreter = i
+	return nil
+}
+
+// undoBlock removes the last block of the session
+func (s *Shell) undoBlock() {
+	n := len(s.workspace.GetCodeBlocks())
+	if n == 0 {
+		fmt.Println("Nothing to undo")
+		return
+	}
+	s.dropBlock(n)
+}
+
+// dropBlock removes block n, numbered from 1, from the session and replays
+// the remaining blocks
+func (s *Shell) dropBlock(n int) {
+	blocks := s.workspace.GetCodeBlocks()
+	if n < 1 || n > len(blocks) {
+		fmt.Printf("No block %d, the session has %d blocks\n", n, len(blocks))
+		return
+	}
+
+	removed := blocks[n-1]
+	i, outputs, err := s.replay(slices.Delete(slices.Clone(blocks), n-1, n))
+	if err != nil {
+		fmt.Printf("Error: the session no longer evaluates without block %d: %v\n", n, err)
+		fmt.Println("Block not removed.")
+		return
+	}
+	if err := s.workspace.RemoveCodeBlock(n, outputs); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	s.interpreter = i
+	fmt.Printf("✓ Removed block %d: %s\n", n, firstLine(removed))
+}
+
+// printBlocks prints the blocks of the session with their numbers, which
+// drop and replace take
+func (s *Shell) printBlocks() {
+	blocks := s.workspace.GetCodeBlocks()
+	if len(blocks) == 0 {
+		fmt.Println("No blocks in the session")
+		return
+	}
+	for i, block := range blocks {
+		fmt.Printf("[%d]\n%s\n\n", i+1, block)
+	}
+}
+
+// replaceBlock lets the user edit block n, numbered from 1, and substitutes
+// the new version when the session still evaluates with it
+func (s *Shell) replaceBlock(n int) {
+	blocks := s.workspace.GetCodeBlocks()
+	if n < 1 || n > len(blocks) {
+		fmt.Printf("No block %d, the session has %d blocks\n", n, len(blocks))
+		return
+	}
+
+	code, err := s.editBlock(n, blocks[n-1])
+	if err != nil {
+		fmt.Printf("Error editing block %d: %v\n", n, err)
+		return
+	}
+	code = strings.TrimSpace(code)
+	if code == "" || code == blocks[n-1] {
+		fmt.Printf("Block %d unchanged\n", n)
+		return
+	}
+
+	updated := slices.Clone(blocks)
+	updated[n-1] = code
+	s.addHistory(code)
+	if err := s.setBlocks(updated); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Printf("Block %d not replaced.\n", n)
+		return
+	}
+	fmt.Printf("✓ Replaced block %d and replayed %d later blocks\n", n, len(blocks)-n)
+}
+
+// editBlock returns a new version of a block, edited in $VISUAL or $EDITOR
+// when set, or typed at the prompt otherwise
+func (s *Shell) editBlock(n int, code string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		fmt.Printf("Block %d:\n%s\n\nEnter the new version of block %d:\n", n, code, n)
+		newCode, cancel, err := s.readCodeBlock()
+		if err != nil || cancel {
+			return "", err
+		}
+		return newCode, nil
+	}
+
+	dir, err := os.MkdirTemp("", "gosh-block-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, fmt.Sprintf("block_%d.go", n))
+	if err := os.WriteFile(path, []byte(code+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write block: %w", err)
+	}
+
+	// The editor command may carry arguments, such as "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", args[0], err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited block: %w", err)
+	}
+	return string(content), nil
+}
+
+// firstLine returns the first line of a block, marking that more follow
+func firstLine(block string) string {
+	line, rest, _ := strings.Cut(block, "\n")
+	if rest != "" {
+		line += " ..."
+	}
+	return line
+}
+
+// setCheckpoint records the current block of the session under a name
+func (s *Shell) setCheckpoint(name string) {
+	if err := s.workspace.SetCheckpoint(name); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Checkpoint %s at block %d\n", name, len(s.workspace.GetCodeBlocks()))
+}
+
+// printCheckpoints lists the checkpoints of the session by block
+func (s *Shell) printCheckpoints() {
+	checkpoints := s.workspace.Meta().Checkpoints
+	if len(checkpoints) == 0 {
+		fmt.Println("No checkpoints in the session")
+		return
+	}
+	names := slices.Sorted(maps.Keys(checkpoints))
+	slices.SortStableFunc(names, func(a, b string) int {
+		return checkpoints[a] - checkpoints[b]
+	})
+	for _, name := range names {
+		fmt.Printf("  %-20s block %d\n", name, checkpoints[name])
+	}
+}
+
+// rollback removes the blocks added since a checkpoint and replays the
+// remaining ones in a new interpreter
+func (s *Shell) rollback(name string) {
+	n, err := s.workspace.Checkpoint(name)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	blocks := s.workspace.GetCodeBlocks()
+	if n == len(blocks) {
+		fmt.Printf("No blocks added since checkpoint %s\n", name)
+		return
+	}
+
+	if err := s.setBlocks(blocks[:n]); err != nil {
+		fmt.Printf("Error: failed to roll back to checkpoint %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("✓ Rolled back to checkpoint %s, removed %d blocks\n", name, len(blocks)-n)
+}
@@ -0,0 +1,196 @@
+package shell
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// addBlocks evaluates blocks and adds them to the session, like the prompt
+func addBlocks(t *testing.T, sh *Shell, blocks ...string) {
+	t.Helper()
+	for _, block := range blocks {
+		sh.output.Reset()
+		if err := sh.execute(block); err != nil {
+			t.Fatalf("Failed to evaluate %q: %v", block, err)
+		}
+		if err := sh.workspace.AddCodeBlockWithOutput(block, sh.output.String()); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+}
+
+func TestUndoAndDrop(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	addBlocks(t, sh, "a := 1", "b := a + 1", `c := "c"`, `fmt.Print(c)`)
+
+	sh.undoBlock()
+	if blocks := sh.workspace.GetCodeBlocks(); len(blocks) != 3 {
+		t.Fatalf("Expected 3 blocks after undo, got %q", blocks)
+	}
+
+	// Block 2 depends on block 1, so block 1 cannot be dropped
+	sh.dropBlock(1)
+	if blocks := sh.workspace.GetCodeBlocks(); len(blocks) != 3 || blocks[0] != "a := 1" {
+		t.Fatalf("A failing drop should keep the session, got %q", blocks)
+	}
+
+	sh.dropBlock(3)
+	sh.dropBlock(7)
+	blocks := sh.workspace.GetCodeBlocks()
+	if len(blocks) != 2 || blocks[1] != "b := a + 1" {
+		t.Fatalf("Unexpected blocks %q", blocks)
+	}
+	saved, err := sh.workspace.LoadSession(sh.workspace.SessionID())
+	if err != nil || len(saved) != 2 {
+		t.Errorf("Session file should be rewritten, got %q (%v)", saved, err)
+	}
+
+	// The interpreter is rebuilt from the remaining blocks
+	if err := sh.execute(`_ = c`); err == nil {
+		t.Error("Dropped variables should be undefined")
+	}
+	if err := sh.execute(`_ = b`); err != nil {
+		t.Errorf("Remaining variables should be defined: %v", err)
+	}
+
+	sh.undoBlock()
+	sh.undoBlock()
+	sh.undoBlock()
+	if blocks := sh.workspace.GetCodeBlocks(); len(blocks) != 0 {
+		t.Errorf("Expected no blocks, got %q", blocks)
+	}
+}
+
+func TestReplaceBlock(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	addBlocks(t, sh, `func greet() string { return "hi" }`, `msg := greet()`, `fmt.Print(msg)`)
+
+	// Without an editor, the new version is read from the prompt
+	sh.reader = bufio.NewReader(strings.NewReader("func greet() string {\n\treturn \"hello\"\n}\n\n"))
+	sh.replaceBlock(1)
+
+	blocks := sh.workspace.GetCodeBlocks()
+	if len(blocks) != 3 || !strings.Contains(blocks[0], `"hello"`) {
+		t.Fatalf("Expected block 1 to be replaced, got %q", blocks)
+	}
+	if outputs := sh.workspace.GetBlockOutputs(); outputs[2] != "hello" {
+		t.Errorf("Later blocks should be replayed, got outputs %q", outputs)
+	}
+
+	// A replacement breaking later blocks is rejected
+	sh.reader = bufio.NewReader(strings.NewReader("func welcome() string { return \"hey\" }\n\n"))
+	sh.replaceBlock(1)
+	if blocks := sh.workspace.GetCodeBlocks(); !strings.Contains(blocks[0], `"hello"`) {
+		t.Errorf("A failing replacement should be rejected, got %q", blocks)
+	}
+	if err := sh.execute(`_ = greet()`); err != nil {
+		t.Errorf("The interpreter should be kept after a rejected replacement: %v", err)
+	}
+}
+
+func TestReplaceBlockEditor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake editor is a shell script")
+	}
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\nsed 's/1/10/' \"$1\" > \"$1.new\" && mv \"$1.new\" \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write editor: %v", err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	addBlocks(t, sh, `x := 1`, `fmt.Print(x * 2)`)
+
+	sh.replaceBlock(1)
+	if blocks := sh.workspace.GetCodeBlocks(); blocks[0] != "x := 10" {
+		t.Errorf("Expected the edited block, got %q", blocks)
+	}
+	if outputs := sh.workspace.GetBlockOutputs(); outputs[1] != "20" {
+		t.Errorf("Expected replayed output 20, got %q", outputs)
+	}
+}
+
+func TestCheckpointRollback(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	addBlocks(t, sh, "a := 1", "b := a + 1")
+	sh.handleBuiltinCommand("checkpoint good")
+	addBlocks(t, sh, `a = 100`, `dead := "end"`)
+
+	sh.handleBuiltinCommand("rollback good")
+	if blocks := sh.workspace.GetCodeBlocks(); len(blocks) != 2 || blocks[1] != "b := a + 1" {
+		t.Fatalf("Expected the blocks up to the checkpoint, got %q", blocks)
+	}
+	sh.output.Reset()
+	if err := sh.execute(`fmt.Print(a)`); err != nil || sh.output.String() != "1" {
+		t.Errorf("Expected the state of the checkpoint, got %q, %v", sh.output.String(), err)
+	}
+	if err := sh.execute(`_ = dead`); err == nil {
+		t.Error("Variables declared after the checkpoint should be gone")
+	}
+
+	// The rollback does not remove the checkpoint, which can be reused
+	if n, err := sh.workspace.Checkpoint("good"); err != nil || n != 2 {
+		t.Errorf("Expected checkpoint good at block 2, got %d, %v", n, err)
+	}
+	sh.handleBuiltinCommand("rollback missing")
+	if len(sh.workspace.GetCodeBlocks()) != 2 {
+		t.Error("Rolling back to an unknown checkpoint should keep the session")
+	}
+
+	// Dropping a block before a checkpoint keeps it after the same blocks
+	addBlocks(t, sh, `c := "c"`)
+	sh.handleBuiltinCommand("checkpoint with-c")
+	addBlocks(t, sh, `d := "d"`)
+	sh.handleBuiltinCommand("drop 2")
+	sh.handleBuiltinCommand("rollback with-c")
+	if blocks := sh.workspace.GetCodeBlocks(); len(blocks) != 2 || blocks[1] != `c := "c"` {
+		t.Errorf("Expected the blocks up to c, got %q", blocks)
+	}
+}
+
+func TestReplayedEffects(t *testing.T) {
+	blocks := []string{
+		"a := 1",
+		"fmt.Println(a)\nb := a + 1",
+		`os.WriteFile("x", nil, 0600)`,
+	}
+	want := []string{
+		"block 2: fmt.Println(a)",
+		`block 3: os.WriteFile("x", nil, 0600)`,
+	}
+	if got := replayedEffects(blocks); !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got := replayedEffects(blocks[:1]); len(got) != 0 {
+		t.Errorf("Expected no side effects, got %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Napolitain/gosh/internal/config"
+	"golang.org/x/term"
+)
+
+// ANSI colors of the default theme
+const (
+	colorPrompt  = "36" // cyan
+	colorSuccess = "32" // green
+	colorError   = "31" // red
+)
+
+// colorize wraps text in an ANSI color when the theme enables colors
+func (s *Shell) colorize(color, text string) string {
+	if !s.colorsEnabled() {
+		return text
+	}
+	return "\033[" + color + "m" + text + "\033[0m"
+}
+
+// colorsEnabled reports whether the theme colors the output
+func (s *Shell) colorsEnabled() bool {
+	switch s.config.Theme {
+	case config.ThemeColor:
+		return true
+	case config.ThemeAuto:
+		_, noColor := os.LookupEnv("NO_COLOR")
+		return !noColor && term.IsTerminal(int(os.Stdout.Fd()))
+	}
+	return false
+}
+
+// prompt returns the prompt of the first line of a code block
+func (s *Shell) prompt() string {
+	return s.colorize(colorPrompt, s.expandPrompt(s.config.Prompt))
+}
+
+// continuationPrompt returns the prompt of the next lines of a code block
+func (s *Shell) continuationPrompt() string {
+	return s.colorize(colorPrompt, s.expandPrompt(s.config.ContinuationPrompt))
+}
+
+// submitOnBlankLine reports whether an empty line submits the code block
+func (s *Shell) submitOnBlankLine() bool {
+	return s.config.SubmitKey == config.SubmitBlankLine || s.config.EditMode == config.EditLine
+}
+
+// addHistory appends blocks to the history, dropping the oldest ones beyond
+// the configured history size
+func (s *Shell) addHistory(blocks ...string) {
+	s.history = append(s.history, blocks...)
+	if size := s.config.HistorySize; size > 0 && len(s.history) > size {
+		s.history = append(s.history[:0], s.history[len(s.history)-size:]...)
+	}
+}
+
+// showConfig prints the effective settings and where each comes from
+func (s *Shell) showConfig() {
+	fmt.Printf("%-20s %-24s %s\n", "SETTING", "VALUE", "SOURCE")
+	for _, key := range config.Keys {
+		fmt.Printf("%-20s %-24s %s\n", key, s.config.Value(key), s.config.Source(key))
+	}
+	fmt.Printf("\nConfig file: %s\n", filepath.Join(s.workspace.Path(), config.FileName))
+	fmt.Println("Settings can be overridden by GOSH_<SETTING> environment variables and --set <setting>=<value> flags")
+}
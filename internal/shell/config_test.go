@@ -0,0 +1,56 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Napolitain/gosh/internal/config"
+)
+
+func TestShellConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("GOSH_HOME", home)
+	t.Setenv("GOSH_TIMEOUT", "200ms")
+
+	content := `{"imports": ["strings"], "history_size": 2, "timeout": "1m"}`
+	if err := os.WriteFile(filepath.Join(home, config.FileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	sh, err := NewWithOptions(Options{Settings: []string{"prompt=>> "}, NoExportPrompt: true})
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+
+	if sh.config.Prompt != ">> " || time.Duration(sh.config.Timeout) != 200*time.Millisecond || sh.config.ExitPrompt {
+		t.Errorf("Unexpected config: %+v", sh.config)
+	}
+	if got := sh.config.Source("exit_prompt"); got != "--no-export-prompt" {
+		t.Errorf("Unexpected exit_prompt source %s", got)
+	}
+
+	// Configured imports replace the default fmt import
+	if err := sh.execute(`_ = strings.ToUpper("x")`); err != nil {
+		t.Errorf("Configured imports should be available: %v", err)
+	}
+	if err := sh.execute(`fmt.Println("x")`); err == nil {
+		t.Error("fmt should not be imported when imports are configured")
+	}
+
+	err = sh.execute("for {}")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+
+	sh.addHistory("a", "b", "c")
+	if len(sh.history) != 2 || sh.history[0] != "b" {
+		t.Errorf("Expected the last 2 blocks in history, got %q", sh.history)
+	}
+
+	if _, err := NewWithOptions(Options{Settings: []string{"theme"}}); err == nil {
+		t.Error("Expected an error for a setting without a value")
+	}
+}
@@ -0,0 +1,231 @@
+package shell
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Napolitain/gosh/internal/workspace"
+)
+
+// handleExport handles the export built-in command
+//
+//	export cli <name> [--flavor=cobra|std] [--standalone] [--vendor] [--flags=a,b] [--out=dir] [--build]
+//	export pkg <name> [--out=dir]
+//	export test <name> [--out=dir]
+func (s *Shell) handleExport(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: export cli <name> [--flavor=cobra|std] [--standalone] [--vendor] [--flags=a,b] [--out=dir] [--build]")
+		fmt.Println("       export pkg <name> [--out=dir]")
+		fmt.Println("       export test <name> [--out=dir]")
+		return
+	}
+
+	switch args[0] {
+	case "cli":
+		s.exportCLI(args[1:])
+	case "pkg":
+		s.exportPackage(args[1:])
+	case "test":
+		s.exportGoldenTest(args[1:])
+	default:
+		fmt.Printf("Unknown export target: %s\n", args[0])
+	}
+}
+
+// exportCLI generates a CLI tool from the session without leaving the shell
+func (s *Shell) exportCLI(args []string) {
+	fs := flag.NewFlagSet("export cli", flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	flavorName := fs.String("flavor", string(workspace.CobraFlavor), "CLI framework: cobra or std")
+	standalone := fs.Bool("standalone", false, "write a dedicated go.mod in the tool directory")
+	vendor := fs.Bool("vendor", false, "vendor dependencies from the local module cache (implies --standalone)")
+	flags := fs.String("flags", "", "comma-separated session variables to expose as flags")
+	out := fs.String("out", "", "target directory (default: <workspace>/cmd/<name>, implies --standalone outside the workspace)")
+	build := fs.Bool("build", false, "run go build on the generated tool")
+
+	positional, err := parseCommandArgs(fs, args)
+	if err != nil {
+		return
+	}
+	if len(positional) != 1 {
+		fmt.Println("Usage: export cli <name> [--flavor=cobra|std] [--standalone] [--vendor] [--flags=a,b] [--out=dir] [--build]")
+		return
+	}
+
+	flavor, err := workspace.ParseFlavor(*flavorName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if len(s.workspace.GetCodeBlocks()) == 0 {
+		fmt.Println("No code blocks to export")
+		return
+	}
+
+	opts := workspace.CLIOptions{
+		Flavor:     flavor,
+		Standalone: *standalone || *vendor,
+		Vendor:     *vendor,
+		Dir:        *out,
+		Build:      *build,
+	}
+	for _, name := range strings.Split(*flags, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			opts.Flags = append(opts.Flags, name)
+		}
+	}
+
+	s.generateCLI(positional[0], opts)
+}
+
+// exportPackage writes the session declarations as a library package
+func (s *Shell) exportPackage(args []string) {
+	fs := flag.NewFlagSet("export pkg", flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	out := fs.String("out", "", "target directory (default: <workspace>/pkg/<name>)")
+
+	positional, err := parseCommandArgs(fs, args)
+	if err != nil {
+		return
+	}
+	if len(positional) != 1 {
+		fmt.Println("Usage: export pkg <name> [--out=dir]")
+		return
+	}
+
+	name := positional[0]
+	s.warnStartupNames()
+	dir, err := s.workspace.GeneratePackage(name, *out)
+	if err != nil {
+		fmt.Printf("Error exporting package: %v\n", err)
+		return
+	}
+
+	fmt.Printf("✓ Package '%s' exported successfully!\n", name)
+	fmt.Printf("  Location: %s\n", dir)
+	if *out == "" {
+		fmt.Printf("  Import path: %s\n", s.workspace.PackageImportPath(name))
+	}
+}
+
+// exportGoldenTest writes a test replaying the session against its output
+func (s *Shell) exportGoldenTest(args []string) {
+	fs := flag.NewFlagSet("export test", flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	out := fs.String("out", "", "target directory (default: <workspace>/pkg/<name>)")
+
+	positional, err := parseCommandArgs(fs, args)
+	if err != nil {
+		return
+	}
+	if len(positional) != 1 {
+		fmt.Println("Usage: export test <name> [--out=dir]")
+		return
+	}
+
+	name := positional[0]
+	s.warnStartupNames()
+	dir, err := s.workspace.GenerateGoldenTest(name, *out)
+	if err != nil {
+		fmt.Printf("Error generating golden test: %v\n", err)
+		return
+	}
+
+	fmt.Printf("✓ Golden test '%s' generated successfully!\n", name)
+	fmt.Printf("  Location: %s\n", dir)
+	fmt.Printf("  To run: cd %s && go test (add -update to refresh the golden files)\n", dir)
+	if *out != "" {
+		fmt.Println("  The target module must require github.com/traefik/yaegi")
+	}
+}
+
+// generateCLI generates a CLI tool and reports where it was written
+func (s *Shell) generateCLI(name string, opts workspace.CLIOptions) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = s.workspace.CLIPath(name)
+	}
+
+	s.warnStartupNames()
+	if err := s.workspace.GenerateCLI(name, opts); err != nil {
+		var compileErr *workspace.CompileError
+		if errors.As(err, &compileErr) {
+			if compileErr.Partial() {
+				fmt.Printf("⚠ CLI tool '%s' was generated but could only be partly verified:\n", name)
+			} else {
+				fmt.Printf("✗ CLI tool '%s' was generated but does not compile:\n", name)
+			}
+			for _, d := range compileErr.Diagnostics {
+				fmt.Printf("  %s\n", d)
+			}
+			fmt.Printf("  Location: %s\n", dir)
+			return
+		}
+		fmt.Printf("Error generating CLI tool: %v\n", err)
+		return
+	}
+
+	fmt.Printf("✓ CLI tool '%s' generated successfully!\n", name)
+	fmt.Printf("  Location: %s\n", dir)
+	fmt.Printf("  To build: cd %s && go build\n", dir)
+}
+
+// warnStartupNames warns when the session uses names declared by the
+// startup file, which exported code does not include
+func (s *Shell) warnStartupNames() {
+	if s.options.NoRC {
+		return
+	}
+	names, err := s.workspace.StartupNamesUsed()
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return
+	}
+	if len(names) > 0 {
+		fmt.Printf("⚠ The session uses %s from %s, which exported code does not include\n",
+			strings.Join(names, ", "), s.workspace.RCPath())
+	}
+}
+
+// parseCommandArgs parses the flags of a built-in command, which may appear
+// before or after its positional arguments, and returns the positional ones
+func parseCommandArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var flagArgs, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+
+		flagArgs = append(flagArgs, arg)
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		// A non-boolean flag given as "--name value" consumes the next argument
+		if f := fs.Lookup(name); f != nil && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+	}
+
+	if err := fs.Parse(flagArgs); err != nil {
+		return nil, err
+	}
+	return positional, nil
+}
+
+// isBoolFlag reports whether a flag can be given without a value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
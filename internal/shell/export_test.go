@@ -0,0 +1,194 @@
+package shell
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCommandArgs(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flavor := fs.String("flavor", "cobra", "")
+	vendor := fs.Bool("vendor", false, "")
+
+	positional, err := parseCommandArgs(fs, []string{"tool", "--flavor", "std", "--vendor"})
+	if err != nil {
+		t.Fatalf("Failed to parse arguments: %v", err)
+	}
+
+	if len(positional) != 1 || positional[0] != "tool" {
+		t.Errorf("Expected positional [tool], got %v", positional)
+	}
+	if *flavor != "std" {
+		t.Errorf("Expected flavor std, got %s", *flavor)
+	}
+	if !*vendor {
+		t.Error("Expected vendor to be set")
+	}
+
+	if _, err := parseCommandArgs(fs, []string{"--unknown"}); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+}
+
+func TestExportCLI(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+
+	if err := sh.workspace.AddCodeBlock(`fmt.Println("exported")`); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	if !sh.handleBuiltinCommand("export cli test_export_cli --flavor=std") {
+		t.Fatal("export should be a builtin command")
+	}
+
+	mainPath := filepath.Join(sh.workspace.CLIPath("test_export_cli"), "main.go")
+	if _, err := os.Stat(mainPath); err != nil {
+		t.Errorf("export cli should generate main.go: %v", err)
+	}
+}
+
+func TestExportCLIOutDir(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+
+	if err := sh.workspace.AddCodeBlock(`fmt.Println("exported")`); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "tool")
+	sh.handleBuiltinCommand("export cli outdir_tool --flavor=std --out=" + dir)
+
+	for _, name := range []string{"main.go", "go.mod"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("export cli --out should write %s: %v", name, err)
+		}
+	}
+}
+
+func TestPromptForCLIGenerationSharesReader(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+
+	if err := sh.workspace.AddCodeBlock(`fmt.Println("prompted")`); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	// Answers come from the reader used for code blocks, as with piped input
+	sh.reader = bufio.NewReader(strings.NewReader("y\nprompt_tool\ns\nw\n"))
+	sh.promptForCLIGeneration()
+
+	mainPath := filepath.Join(sh.workspace.CLIPath("prompt_tool"), "main.go")
+	if _, err := os.Stat(mainPath); err != nil {
+		t.Errorf("exit prompt should generate main.go: %v", err)
+	}
+}
+
+func TestNoExportPrompt(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := NewWithOptions(Options{NoExportPrompt: true})
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+
+	if err := sh.workspace.AddCodeBlock(`fmt.Println("scripted")`); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	input := strings.NewReader("y\nskipped_tool\n")
+	sh.reader = bufio.NewReader(input)
+	sh.promptForCLIGeneration()
+
+	if input.Len() != len("y\nskipped_tool\n") {
+		t.Error("No input should be read when the export prompt is disabled")
+	}
+	if _, err := os.Stat(sh.workspace.CLIPath("skipped_tool")); err == nil {
+		t.Error("No CLI tool should be generated when the export prompt is disabled")
+	}
+}
+
+func TestExportPackageImportable(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	if err := sh.workspace.AddCodeBlock("func Double(n int) int {\n\treturn n * 2\n}"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if !sh.handleBuiltinCommand("export pkg helpers") {
+		t.Fatal("export should be a builtin command")
+	}
+
+	// A later session imports the package from the workspace
+	later, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	later.output.Reset()
+	for _, block := range []string{`import "gosh/pkg/helpers"`, `fmt.Println(helpers.Double(21))`} {
+		if err := later.execute(block); err != nil {
+			t.Fatalf("Failed to execute %q: %v", block, err)
+		}
+	}
+	if got := later.output.String(); got != "42\n" {
+		t.Errorf("Expected the exported function to run, got %q", got)
+	}
+}
+
+func TestExportGoldenTestCapturesOutput(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+
+	for _, block := range []string{`x := 21`, `fmt.Println(x * 2)`} {
+		sh.output.Reset()
+		if err := sh.execute(block); err != nil {
+			t.Fatalf("Failed to execute %q: %v", block, err)
+		}
+		if err := sh.workspace.AddCodeBlockWithOutput(block, sh.output.String()); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	outputs := sh.workspace.GetBlockOutputs()
+	if outputs[0] != "" || outputs[1] != "42\n" {
+		t.Fatalf("Unexpected captured outputs: %q", outputs)
+	}
+
+	dir := t.TempDir()
+	if !sh.handleBuiltinCommand("export test golden_demo --out=" + dir) {
+		t.Fatal("export should be a builtin command")
+	}
+
+	golden, err := os.ReadFile(filepath.Join(dir, "testdata", "goldendemo", "block_002.golden"))
+	if err != nil {
+		t.Fatalf("Golden file not written: %v", err)
+	}
+	if string(golden) != "42\n" {
+		t.Errorf("Golden file should hold the block output, got %q", golden)
+	}
+}
@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/Napolitain/gosh/internal/workspace"
+	"github.com/traefik/yaegi/stdlib"
+)
+
+// stdlibPackages holds the import paths of the standard library packages
+// available to the interpreter
+var stdlibPackages = func() map[string]bool {
+	packages := make(map[string]bool, len(stdlib.Symbols))
+	for key := range stdlib.Symbols {
+		// Keys are "<import path>/<package name>"
+		if i := strings.LastIndex(key, "/"); i > 0 {
+			packages[key[:i]] = true
+		}
+	}
+	return packages
+}()
+
+// blockImports returns the import paths of the import declarations of a
+// code block
+func blockImports(code string) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+code, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// importHint explains why the third-party imports of a failed code block
+// may not be loadable, or returns an empty string
+func (s *Shell) importHint(code string) string {
+	var hints []string
+	for _, path := range blockImports(code) {
+		switch {
+		case stdlibPackages[path]:
+			continue
+		case path == "C":
+			hints = append(hints, "cgo is not supported by the interpreter")
+			continue
+		case path == "unsafe":
+			hints = append(hints, "package unsafe is not supported by the interpreter")
+			continue
+		}
+		_, found := s.sources.PackageDir(path)
+		if !found && strings.HasPrefix(path, workspace.WorkspaceModule+"/") {
+			hints = append(hints, fmt.Sprintf("package %s was not found in the workspace; export it from a session with 'export pkg'", path))
+			continue
+		}
+		if !found {
+			hints = append(hints, fmt.Sprintf("package %s was not found in the current module, its vendor directory or the module cache; download it with 'go get %s' or 'go mod download'", path, path))
+			continue
+		}
+		if err := s.sources.FindUnsupported(path); err != nil {
+			hints = append(hints, fmt.Sprintf("%v; precompile it with 'gosh extract %s'", err, err.ImportPath))
+		}
+	}
+	return strings.Join(hints, "\n")
+}
@@ -0,0 +1,101 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/traefik/yaegi/interp"
+)
+
+// writeFiles writes the given files under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestBlockImports(t *testing.T) {
+	code := "import (\n\t\"fmt\"\n\tstr \"strings\"\n)\n\nfmt.Println(str.ToUpper(\"x\"))"
+	if got := blockImports(code); !slices.Equal(got, []string{"fmt", "strings"}) {
+		t.Errorf("Unexpected imports %q", got)
+	}
+	if got := blockImports("x := 1"); len(got) != 0 {
+		t.Errorf("Expected no imports, got %q", got)
+	}
+}
+
+func TestModuleCacheImport(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	cache := t.TempDir()
+	t.Setenv("GOMODCACHE", cache)
+	writeFiles(t, cache, map[string]string{
+		"example.com/shout@v1.0.0/go.mod":     "module example.com/shout\n",
+		"example.com/shout@v1.0.0/shout.go":   "package shout\n\nimport \"strings\"\n\nfunc Shout(s string) string { return strings.ToUpper(s) + \"!\" }\n",
+		"example.com/native@v1.0.0/go.mod":    "module example.com/native\n",
+		"example.com/native@v1.0.0/native.go": "package native\n\n// int one() { return 1; }\nimport \"C\"\n\nfunc One() int { return int(C.one()) }\n",
+	})
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+
+	if err := sh.execute(`import "example.com/shout"`); err != nil {
+		t.Fatalf("Failed to import cached package: %v", err)
+	}
+	sh.output.Reset()
+	if err := sh.execute(`fmt.Print(shout.Shout("hi"))`); err != nil {
+		t.Fatalf("Failed to call cached package: %v", err)
+	}
+	if got := sh.output.String(); got != "HI!" {
+		t.Errorf("Unexpected output %q", got)
+	}
+
+	tests := map[string]string{
+		`import "example.com/native"`:  "uses cgo",
+		`import "example.com/missing"`: "go get example.com/missing",
+	}
+	for code, want := range tests {
+		err := sh.execute(code)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", code, want, err)
+		}
+	}
+}
+
+func TestPrecompiledSymbols(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := NewWithOptions(Options{
+		NoModule: true,
+		Symbols: interp.Exports{
+			"example.com/answer/answer": {"Value": reflect.ValueOf(func() int { return 42 })},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+
+	if err := sh.execute(`import "example.com/answer"`); err != nil {
+		t.Fatalf("Failed to import precompiled package: %v", err)
+	}
+	if err := sh.execute(`fmt.Print(answer.Value())`); err != nil {
+		t.Fatalf("Failed to use precompiled package: %v", err)
+	}
+	if got := sh.output.String(); got != "42" {
+		t.Errorf("Unexpected output %q", got)
+	}
+}
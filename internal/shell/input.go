@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// errInterrupted is returned by readLine when a signal arrives while waiting
+// for input
+var errInterrupted = errors.New("interrupted")
+
+// lineResult is the outcome of a line read from standard input
+type lineResult struct {
+	line string
+	err  error
+}
+
+// readLine reads a line from the shared reader. The read runs in its own
+// goroutine so that a signal can interrupt the wait; the read then stays in
+// flight and the next call picks its line up, so that only one goroutine
+// ever reads s.reader and no input is lost.
+func (s *Shell) readLine() (string, error) {
+	if s.pending == nil {
+		pending := make(chan lineResult, 1)
+		reader := s.reader
+		go func() {
+			line, err := reader.ReadString('\n')
+			pending <- lineResult{line: line, err: err}
+		}()
+		s.pending = pending
+	}
+
+	select {
+	case result := <-s.pending:
+		s.pending = nil
+		return result.line, result.err
+	case <-s.interrupts:
+		return "", errInterrupted
+	}
+}
+
+// interrupted reports whether a signal arrived since the last check
+func (s *Shell) interrupted() bool {
+	select {
+	case <-s.interrupts:
+		return true
+	default:
+		return false
+	}
+}
+
+// cancelOnInterrupt returns a context canceled when a signal arrives, so
+// that Ctrl+C stops the evaluation of a block rather than the shell. Other
+// signals are passed on to the main loop, which exits.
+func (s *Shell) cancelOnInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if s.interrupts == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case sig := <-s.interrupts:
+			cancel()
+			if sig != os.Interrupt {
+				select {
+				case s.interrupts <- sig:
+				default:
+				}
+			}
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,55 @@
+package shell
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLineInterrupted(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	r, w := io.Pipe()
+	defer w.Close()
+	sh.reader = bufio.NewReader(r)
+	sh.interrupts = make(chan os.Signal, 1)
+
+	sh.interrupts <- os.Interrupt
+	if _, err := sh.readLine(); err != errInterrupted {
+		t.Fatalf("Expected the read to be interrupted, got %v", err)
+	}
+
+	// The interrupted read is still the only reader, and its line goes to
+	// the next prompt
+	go w.Write([]byte("y\n"))
+	line, err := sh.readLine()
+	if err != nil || line != "y\n" {
+		t.Errorf("Expected the pending line, got %q, %v", line, err)
+	}
+}
+
+func TestExecuteInterrupted(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	sh.interrupts = make(chan os.Signal, 1)
+	time.AfterFunc(100*time.Millisecond, func() { sh.interrupts <- os.Interrupt })
+
+	err = sh.execute("for {\n}")
+	if err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("Expected the evaluation to be interrupted, got %v", err)
+	}
+	if sh.interrupted() {
+		t.Error("Ctrl+C should only stop the evaluation, not the shell")
+	}
+}
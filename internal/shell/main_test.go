@@ -0,0 +1,23 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestMain points GOSH_HOME at a temporary directory for the whole package,
+// so that a test creating a workspace without its own GOSH_HOME never writes
+// to, or prunes, the sessions of the real ~/.gosh
+func TestMain(m *testing.M) {
+	home, err := os.MkdirTemp("", "gosh-test-home-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create test home: %v\n", err)
+		os.Exit(1)
+	}
+	os.Setenv("GOSH_HOME", home)
+
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
@@ -0,0 +1,167 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lastBlock is the outcome of the last code block evaluated at the prompt
+type lastBlock struct {
+	evaluated bool
+	failed    bool
+	duration  time.Duration
+}
+
+// promptSegments computes the value of each prompt segment
+var promptSegments = map[string]func(s *Shell) string{
+	"session": func(s *Shell) string {
+		if name := s.workspace.Meta().Name; name != "" {
+			return name
+		}
+		return s.workspace.SessionID()
+	},
+	"block": func(s *Shell) string {
+		return strconv.Itoa(len(s.workspace.GetCodeBlocks()) + 1)
+	},
+	"cwd": func(s *Shell) string {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return ""
+		}
+		return shortenHome(cwd)
+	},
+	"branch": func(s *Shell) string {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return ""
+		}
+		return gitBranch(cwd)
+	},
+	"duration": func(s *Shell) string {
+		if !s.last.evaluated {
+			return ""
+		}
+		return formatDuration(s.last.duration)
+	},
+	"status": func(s *Shell) string {
+		switch {
+		case !s.last.evaluated:
+			return ""
+		case s.last.failed:
+			return "✗"
+		}
+		return "✓"
+	},
+}
+
+// expandPrompt replaces the {segment} placeholders of a prompt template.
+// Segments are only computed when the template uses them, and unknown
+// placeholders are kept as typed.
+func (s *Shell) expandPrompt(template string) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+
+	var b strings.Builder
+	for {
+		end := strings.Index(template, "}")
+		if end < 0 {
+			break
+		}
+		start := strings.LastIndex(template[:end], "{")
+		if start < 0 {
+			b.WriteString(template[:end+1])
+			template = template[end+1:]
+			continue
+		}
+
+		b.WriteString(template[:start])
+		if segment, ok := promptSegments[template[start+1:end]]; ok {
+			b.WriteString(segment(s))
+		} else {
+			b.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+	b.WriteString(template)
+	return b.String()
+}
+
+// shortenHome replaces the home directory prefix of path with ~
+func shortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if rel, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return "~" + string(filepath.Separator) + rel
+	}
+	return path
+}
+
+// gitBranch returns the branch checked out in the git repository holding dir,
+// the short commit hash when the HEAD is detached, or an empty string outside
+// a repository. It reads .git directly rather than running git on every
+// prompt.
+func gitBranch(dir string) string {
+	for {
+		gitDir := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitDir); err == nil {
+			if !info.IsDir() {
+				// Worktrees and submodules point to their git directory
+				content, err := os.ReadFile(gitDir)
+				if err != nil {
+					return ""
+				}
+				target, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: ")
+				if !ok {
+					return ""
+				}
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(dir, target)
+				}
+				gitDir = target
+			}
+			return headBranch(gitDir)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// headBranch reads the HEAD of a git directory
+func headBranch(gitDir string) string {
+	content, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(content))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		return strings.TrimPrefix(ref, "refs/heads/")
+	}
+	if len(head) > 7 {
+		return head[:7]
+	}
+	return head
+}
+
+// formatDuration formats the duration of a block for the prompt
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
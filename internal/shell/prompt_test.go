@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExpandPrompt(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	if err := sh.workspace.AddCodeBlock("x := 1"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	if got := sh.expandPrompt("[{session}] "); got != "["+sh.workspace.SessionID()+"] " {
+		t.Errorf("Expected the session ID in %q", got)
+	}
+	if err := sh.workspace.SetName("demo"); err != nil {
+		t.Fatalf("Failed to name session: %v", err)
+	}
+
+	tests := map[string]string{
+		"gosh> ":                "gosh> ",
+		"{session}:{block}> ":   "demo:2> ",
+		"{status}{duration} > ": " > ",
+		"{unknown} {block":      "{unknown} {block",
+		"{{block}}":             "{2}",
+	}
+	for template, want := range tests {
+		if got := sh.expandPrompt(template); got != want {
+			t.Errorf("expandPrompt(%q) = %q, want %q", template, got, want)
+		}
+	}
+
+	sh.last = lastBlock{evaluated: true, failed: true, duration: 1234567 * time.Nanosecond}
+	if got, want := sh.expandPrompt("{status} {duration}> "), "✗ 1ms> "; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	sh.last.failed = false
+	if got, want := sh.expandPrompt("{status}"), "✓"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestGitBranch(t *testing.T) {
+	repo := t.TempDir()
+	writeFiles(t, repo, map[string]string{
+		".git/HEAD":              "ref: refs/heads/feature/prompt\n",
+		"src/pkg/file.go":        "package pkg\n",
+		"worktree/.git":          "gitdir: ../.git/worktrees/wt\n",
+		".git/worktrees/wt/HEAD": "0123456789abcdef0123456789abcdef01234567\n",
+	})
+
+	tests := map[string]string{
+		repo:                              "feature/prompt",
+		filepath.Join(repo, "src", "pkg"): "feature/prompt",
+		filepath.Join(repo, "worktree"):   "0123456",
+	}
+	for dir, want := range tests {
+		if got := gitBranch(dir); got != want {
+			t.Errorf("gitBranch(%s) = %q, want %q", dir, got, want)
+		}
+	}
+
+	if got := gitBranch(t.TempDir()); got != "" {
+		t.Errorf("Expected no branch outside a repository, got %q", got)
+	}
+}
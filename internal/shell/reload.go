@@ -0,0 +1,54 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Napolitain/gosh/internal/workspace"
+)
+
+// rerunPolicy is how reload handles the statements with side effects of the
+// remaining blocks
+type rerunPolicy int
+
+const (
+	rerunAsk rerunPolicy = iota
+	rerunAlways
+	rerunNever
+)
+
+// askRerun asks whether to re-run the statements with side effects of block
+// n, unless an earlier answer covers every remaining block. It returns the
+// decision and the policy for the next blocks. No answer, as when standard
+// input is closed, keeps the side effects from running again.
+func (s *Shell) askRerun(n int, block workspace.ReloadBlock, policy rerunPolicy) (bool, rerunPolicy) {
+	switch policy {
+	case rerunAlways:
+		return true, policy
+	case rerunNever:
+		return false, policy
+	}
+
+	fmt.Printf("Block %d has statements with side effects:\n", n)
+	for _, step := range block.Steps {
+		if step.SideEffects {
+			fmt.Printf("  %s\n", firstLine(step.Code))
+		}
+	}
+	fmt.Print("Re-run them? [y]es, [N]o, [a]ll blocks, [s]kip all blocks: ")
+	answer, err := s.readLine()
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false, rerunNever
+	}
+
+	switch strings.TrimSpace(strings.ToLower(answer)) {
+	case "y", "yes":
+		return true, policy
+	case "a", "all":
+		return true, rerunAlways
+	case "s", "skip":
+		return false, rerunNever
+	}
+	return false, policy
+}
@@ -0,0 +1,114 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReloadSideEffects(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	marked := filepath.Join(dir, "marked")
+	asked := filepath.Join(dir, "asked")
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	addBlocks(t, sh,
+		"import \"os\"",
+		"func twice(s string) string { return s + s }",
+		`word := twice("go")`,
+		fmt.Sprintf("os.WriteFile(%q, []byte(word), 0644)", asked),
+		fmt.Sprintf("//gosh:rerun\nos.WriteFile(%q, nil, 0644)", marked),
+		fmt.Sprintf("//gosh:once\nos.Remove(%q)", asked),
+	)
+
+	tests := []struct {
+		name   string
+		answer string
+		want   bool
+	}{
+		{name: "Declined", answer: "n\n", want: false},
+		{name: "Closed input", answer: "", want: false},
+		{name: "Accepted", answer: "y\n", want: true},
+		{name: "All blocks", answer: "a\n", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(asked)
+			os.Remove(marked)
+			sh.reader = bufio.NewReader(strings.NewReader(tt.answer))
+
+			if err := sh.reloadWorkspace(); err != nil {
+				t.Fatalf("Failed to reload: %v", err)
+			}
+			if _, err := os.Stat(marked); err != nil {
+				t.Errorf("Blocks marked %s should be re-run: %v", "//gosh:rerun", err)
+			}
+			// Block 6, marked //gosh:once, never removes the file again
+			if _, err := os.Stat(asked); (err == nil) != tt.want {
+				t.Errorf("Expected block 4 re-run to be %v, stat error %v", tt.want, err)
+			}
+			if err := sh.execute(`_ = twice(word)`); err != nil {
+				t.Errorf("Declarations should be evaluated again: %v", err)
+			}
+		})
+	}
+
+	os.Remove(asked)
+	if err := sh.reloadAll(); err != nil {
+		t.Fatalf("Failed to reload every block: %v", err)
+	}
+	if _, err := os.Stat(asked); err == nil {
+		t.Error("Reloading every block should re-run block 6 too")
+	}
+}
+
+func TestReloadSkipsDependentSteps(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	addBlocks(t, sh,
+		"import \"os\"",
+		fmt.Sprintf("data, _ := os.ReadFile(%q)", path),
+		"n := len(data)",
+		"func size() int { return n * 2 }",
+		"total := size()",
+		"other := 1",
+	)
+
+	sh.reader = bufio.NewReader(strings.NewReader("n\n"))
+	if err := sh.reloadWorkspace(); err != nil {
+		t.Fatalf("Reload should skip the steps using skipped results: %v", err)
+	}
+	if err := sh.execute(`_ = other`); err != nil {
+		t.Errorf("Independent steps should be evaluated again: %v", err)
+	}
+	if err := sh.execute(`_ = total`); err == nil {
+		t.Error("Steps using skipped results should not be evaluated")
+	}
+
+	// Answering yes evaluates the whole chain again
+	sh.reader = bufio.NewReader(strings.NewReader("y\n"))
+	if err := sh.reloadWorkspace(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	sh.output.Reset()
+	if err := sh.execute(`fmt.Print(total)`); err != nil || sh.output.String() != "6" {
+		t.Errorf("Expected total 6, got %q, %v", sh.output.String(), err)
+	}
+}
@@ -0,0 +1,236 @@
+package shell
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleSessions handles the sessions built-in command
+//
+//	sessions list
+//	sessions show <id>
+//	sessions rm <id>
+//	sessions prune [--older-than=30d]
+func (s *Shell) handleSessions(args []string) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		s.listSessions()
+	case "show":
+		if len(args) != 2 {
+			fmt.Println("Usage: sessions show <id>")
+			return
+		}
+		s.showSession(args[1])
+	case "rm", "remove":
+		if len(args) < 2 {
+			fmt.Println("Usage: sessions rm <id>...")
+			return
+		}
+		for _, ref := range args[1:] {
+			id, err := s.workspace.ResolveSession(ref)
+			if err == nil {
+				err = s.workspace.RemoveSession(id)
+			}
+			if err != nil {
+				fmt.Printf("Error removing session: %v\n", err)
+				continue
+			}
+			fmt.Printf("Removed session %s\n", id)
+		}
+	case "prune":
+		s.pruneSessions(args[1:])
+	default:
+		fmt.Printf("Unknown sessions command: %s\n", args[0])
+		fmt.Println("Usage: sessions [list | show <id> | rm <id> | prune --older-than=30d]")
+	}
+}
+
+// listSessions prints the saved sessions, most recent first
+func (s *Shell) listSessions() {
+	sessions, err := s.workspace.Sessions()
+	if err != nil {
+		fmt.Printf("Error listing sessions: %v\n", err)
+		return
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No saved sessions")
+		return
+	}
+
+	fmt.Printf("  %-22s %-16s %-16s %6s %8s  %s\n", "ID", "NAME", "MODIFIED", "BLOCKS", "SIZE", "TAGS")
+	for _, session := range sessions {
+		marker := " "
+		if session.ID == s.workspace.SessionID() {
+			marker = "*"
+		}
+		fmt.Printf("%s %-22s %-16s %-16s %6d %8s  %s\n", marker, session.ID, session.Name,
+			session.Modified.Format("2006-01-02 15:04"), session.Blocks, formatSize(session.Size),
+			strings.Join(session.Tags, ","))
+	}
+}
+
+// showSession prints the details and code blocks of a saved session
+func (s *Shell) showSession(ref string) {
+	id, err := s.workspace.ResolveSession(ref)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	info, err := s.workspace.Session(id)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	blocks, err := s.workspace.LoadSession(id)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Session %s\n", info.ID)
+	if info.Name != "" {
+		fmt.Printf("  Name:        %s\n", info.Name)
+	}
+	if info.Description != "" {
+		fmt.Printf("  Description: %s\n", info.Description)
+	}
+	if len(info.Tags) > 0 {
+		fmt.Printf("  Tags:        %s\n", strings.Join(info.Tags, ", "))
+	}
+	fmt.Printf("  Modified:    %s\n", info.Modified.Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Blocks:      %d\n", info.Blocks)
+	fmt.Printf("  Size:        %s\n", formatSize(info.Size))
+	for i, block := range blocks {
+		fmt.Printf("\n[%d]\n%s\n", i+1, block)
+	}
+}
+
+// forkSession continues the session in a named copy, so that the original
+// session can later be resumed from the point of the fork
+func (s *Shell) forkSession(name string) {
+	original := s.workspace.SessionID()
+	if err := s.workspace.Fork(name); err != nil {
+		fmt.Printf("Error forking session: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Forked session %s into %s (%s)\n", original, s.workspace.SessionID(), name)
+}
+
+// handleSessionMeta handles the name, tag, untag and describe built-in
+// commands, which edit the metadata of the current session
+func (s *Shell) handleSessionMeta(command, args string) {
+	var err error
+	switch command {
+	case "name":
+		if args == "" {
+			if name := s.workspace.Meta().Name; name != "" {
+				fmt.Printf("Session name: %s\n", name)
+			} else {
+				fmt.Println("Usage: name <name>")
+			}
+			return
+		}
+		err = s.workspace.SetName(args)
+	case "tag", "untag":
+		tags := strings.Fields(args)
+		if len(tags) == 0 {
+			fmt.Printf("Usage: %s <tag>...\n", command)
+			return
+		}
+		if command == "tag" {
+			err = s.workspace.AddTags(tags...)
+		} else {
+			err = s.workspace.RemoveTags(tags...)
+		}
+	case "describe":
+		err = s.workspace.SetDescription(args)
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	meta := s.workspace.Meta()
+	switch command {
+	case "name":
+		fmt.Printf("Session named %s\n", meta.Name)
+	case "tag", "untag":
+		fmt.Printf("Session tags: %s\n", strings.Join(meta.Tags, ", "))
+	case "describe":
+		fmt.Println("Session description updated")
+	}
+}
+
+// pruneSessions removes the sessions older than the given age
+func (s *Shell) pruneSessions(args []string) {
+	fs := flag.NewFlagSet("sessions prune", flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	olderThan := fs.String("older-than", "30d", "minimum age of the removed sessions, such as 30d or 12h")
+
+	positional, err := parseCommandArgs(fs, args)
+	if err != nil {
+		return
+	}
+	if len(positional) != 0 {
+		fmt.Println("Usage: sessions prune [--older-than=30d]")
+		return
+	}
+
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	removed, err := s.workspace.PruneSessions(age)
+	for _, id := range removed {
+		fmt.Printf("Removed session %s\n", id)
+	}
+	if err != nil {
+		fmt.Printf("Error pruning sessions: %v\n", err)
+		return
+	}
+	fmt.Printf("Pruned %d sessions older than %s\n", len(removed), *olderThan)
+}
+
+// parseAge parses a duration that may also be given in days (30d) or
+// weeks (2w), in addition to the units of time.ParseDuration
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// formatSize formats a size in bytes for humans
+func formatSize(size int64) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%d B", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	}
+}
@@ -0,0 +1,68 @@
+package shell
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30d", want: 30 * 24 * time.Hour},
+		{input: "2w", want: 14 * 24 * time.Hour},
+		{input: "12h", want: 12 * time.Hour},
+		{input: "0s", want: 0},
+		{input: "xd", wantErr: true},
+		{input: "-1d", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAge(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAge(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:             "512 B",
+		2048:            "2.0 KB",
+		3 * 1024 * 1024: "3.0 MB",
+	}
+	for size, want := range tests {
+		if got := formatSize(size); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
+
+func TestForkSession(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	addBlocks(t, sh, "setup := 1")
+	original := sh.workspace.SessionID()
+
+	sh.handleBuiltinCommand("fork other")
+	if sh.workspace.SessionID() == original || sh.workspace.Meta().Name != "other" {
+		t.Fatalf("Expected a new session named other, got %s %+v", sh.workspace.SessionID(), sh.workspace.Meta())
+	}
+
+	// The interpreter state carries over to the fork
+	addBlocks(t, sh, "next := setup + 1")
+	if blocks, err := sh.workspace.LoadSession(original); err != nil || len(blocks) != 1 {
+		t.Errorf("The original session should keep 1 block, got %q, %v", blocks, err)
+	}
+}
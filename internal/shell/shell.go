@@ -2,14 +2,23 @@ package shell
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/Napolitain/gosh/internal/config"
+	"github.com/Napolitain/gosh/internal/gomod"
 	"github.com/Napolitain/gosh/internal/workspace"
 	"github.com/traefik/yaegi/interp"
 	"github.com/traefik/yaegi/stdlib"
@@ -21,30 +30,214 @@ type Shell struct {
 	interpreter *interp.Interpreter
 	workspace   *workspace.Workspace
 	history     []string
+	output      bytes.Buffer  // standard output of the block being evaluated
+	console     consoleWriter // terminal side of the interpreter output
+	reader      *bufio.Reader // buffered standard input, shared by every prompt
+	options     Options
+	config      *config.Config
+	last        lastBlock       // outcome of the last evaluated block, shown by the prompt
+	module      *gomod.Module   // Go module gosh was started in, if any
+	sources     *gomod.SourceFS // source of the packages importable from the session
+	interrupts  chan os.Signal  // signals handled by the main loop, nil outside Run
+	pending     chan lineResult // line read in flight, see readLine
 }
 
-// New creates a new Shell instance
+// Options configures a Shell
+type Options struct {
+	// NoExportPrompt disables the question asked on exit about saving the
+	// session as a CLI tool, for scripted sessions. It overrides the
+	// exit_prompt setting.
+	NoExportPrompt bool
+
+	// Config holds the shell settings. When nil, they are loaded from the
+	// config file of the workspace and the environment.
+	Config *config.Config
+
+	// Settings override the configuration, as key=value pairs given by flags
+	Settings []string
+
+	// Resume continues a saved session on start: the one named by ResumeID,
+	// or the most recent one when ResumeID is empty
+	Resume   bool
+	ResumeID string
+
+	// Session names the session on start, so it can later be resumed by name
+	Session string
+
+	// Workspace is the workspace directory, workspace.DefaultPath() when empty
+	Workspace string
+
+	// NoModule disables importing the packages of the Go module containing
+	// the current directory
+	NoModule bool
+
+	// NoRC skips the startup file of the workspace
+	NoRC bool
+
+	// Symbols are precompiled packages importable from the session, see
+	// `gosh extract`
+	Symbols interp.Exports
+}
+
+// New creates a new Shell instance with default options
 func New() (*Shell, error) {
-	ws, err := workspace.New()
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions creates a new Shell instance configured by opts
+func NewWithOptions(opts Options) (*Shell, error) {
+	workspaceDir := opts.Workspace
+	if workspaceDir == "" {
+		dir, err := workspace.DefaultPath()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workspace: %w", err)
+		}
+		workspaceDir = dir
+	}
+
+	ws, err := workspace.NewAt(workspaceDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create workspace: %w", err)
 	}
 
-	i := interp.New(interp.Options{})
+	s := &Shell{
+		workspace: ws,
+		history:   make([]string, 0),
+		reader:    bufio.NewReader(os.Stdin),
+		options:   opts,
+	}
+	if err := s.loadConfig(); err != nil {
+		return nil, err
+	}
+
+	if !opts.NoModule {
+		if err := s.loadModule(); err != nil {
+			return nil, err
+		}
+	}
+	s.sources = gomod.NewSourceFS(s.module)
+	// Packages exported from earlier sessions
+	s.sources.Add(workspace.WorkspaceModule, s.workspace.Path())
+
+	i, err := s.newInterpreter()
+	if err != nil {
+		return nil, err
+	}
+	s.interpreter = i
+
+	return s, nil
+}
+
+// loadConfig loads the settings of the shell, from lowest to highest
+// precedence: defaults, config file, environment variables and flags
+func (s *Shell) loadConfig() error {
+	cfg := s.options.Config
+	if cfg == nil {
+		var err error
+		if cfg, err = config.Load(filepath.Join(s.workspace.Path(), config.FileName)); err != nil {
+			return err
+		}
+		if err := cfg.ApplyEnv(); err != nil {
+			return err
+		}
+	}
+
+	for _, setting := range s.options.Settings {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return fmt.Errorf("invalid setting %q, use key=value", setting)
+		}
+		if err := cfg.Set(key, value, "--set"); err != nil {
+			return err
+		}
+	}
+	if s.options.NoExportPrompt {
+		if err := cfg.Set("exit_prompt", "false", "--no-export-prompt"); err != nil {
+			return err
+		}
+	}
+
+	s.config = cfg
+	return nil
+}
+
+// loadModule makes the packages of the Go module containing the current
+// directory importable from the session
+func (s *Shell) loadModule() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	module, err := gomod.Find(cwd)
+	if err != nil {
+		return fmt.Errorf("failed to load Go module: %w", err)
+	}
+	if module == nil {
+		return nil
+	}
+
+	s.module = module
+	return nil
+}
+
+// newInterpreter creates an interpreter with the standard library loaded and
+// its output also recorded in s.output
+func (s *Shell) newInterpreter() (*interp.Interpreter, error) {
+	// Imports that are not in the standard library are loaded from source:
+	// the current module, its dependencies, the workspace and the module cache
+	opts := interp.Options{
+		Stdout:               io.MultiWriter(&s.console, &s.output),
+		GoPath:               gomod.GoPath,
+		SourcecodeFilesystem: s.sources,
+	}
+
+	i := interp.New(opts)
 	if err := i.Use(stdlib.Symbols); err != nil {
 		return nil, fmt.Errorf("failed to load standard library: %w", err)
 	}
+	if len(s.options.Symbols) > 0 {
+		if err := i.Use(s.options.Symbols); err != nil {
+			return nil, fmt.Errorf("failed to load precompiled packages: %w", err)
+		}
+	}
 
 	// Pre-import commonly used packages
-	if _, err := i.Eval(`import "fmt"`); err != nil {
-		return nil, fmt.Errorf("failed to import fmt: %w", err)
+	for _, path := range s.config.Imports {
+		if _, err := i.Eval(fmt.Sprintf("import %q", path)); err != nil {
+			fmt.Printf("Warning: failed to import %s: %v\n", path, err)
+		}
 	}
 
-	return &Shell{
-		interpreter: i,
-		workspace:   ws,
-		history:     make([]string, 0),
-	}, nil
+	// Exported code imports the packages imported here when the session
+	// uses them
+	imports := slices.Clone(s.config.Imports)
+	if !s.options.NoRC {
+		imports = append(imports, s.evalStartupFile(i)...)
+	}
+	s.workspace.SetImports(imports)
+
+	return i, nil
+}
+
+// evalStartupFile evaluates the startup file of the workspace into i and
+// returns the packages it imports. Errors are reported but do not prevent
+// the shell from starting.
+func (s *Shell) evalStartupFile(i *interp.Interpreter) []string {
+	blocks, err := s.workspace.StartupBlocks()
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return nil
+	}
+	var imports []string
+	for _, block := range blocks {
+		if _, err := i.Eval(block.Code); err != nil {
+			fmt.Printf("Warning: %s:%d: %v\n", s.workspace.RCPath(), block.Line, err)
+			continue
+		}
+		imports = append(imports, blockImports(block.Code)...)
+	}
+	return imports
 }
 
 // Run starts the interactive shell loop
@@ -55,29 +248,57 @@ func (s *Shell) Run() error {
 		ctrlKey = "Cmd"
 	}
 	
+	defer func() {
+		if err := s.workspace.Close(); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}()
+
 	fmt.Println("Welcome to gosh - Go Shell")
 	fmt.Println("Write multi-line code blocks - press Enter for new lines")
-	fmt.Printf("Press %s+Enter to execute your code block\n", ctrlKey)
+	if s.submitOnBlankLine() {
+		fmt.Println("Press Enter on an empty line to execute your code block")
+	} else {
+		fmt.Printf("Press %s+Enter to execute your code block\n", ctrlKey)
+	}
 	fmt.Println("Type 'help' for commands, 'exit' to quit")
+	if s.module != nil {
+		fmt.Printf("Packages of module %s can be imported (%s)\n", s.module.Path, s.module.Dir)
+	}
+	if binary, ok := s.workspace.BundleBinary(); ok && len(s.options.Symbols) == 0 {
+		fmt.Printf("Precompiled packages of this workspace are available in %s\n", binary)
+	}
 	fmt.Println()
 
-	// Handle Ctrl+C gracefully
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-sigChan
-		fmt.Println()
-		s.promptForCLIGeneration()
-		os.Exit(0)
-	}()
+	if s.options.Resume {
+		if err := s.resumeSession(s.options.ResumeID); err != nil {
+			return fmt.Errorf("failed to resume session: %w", err)
+		}
+	}
+	if s.options.Session != "" {
+		if err := s.workspace.SetName(s.options.Session); err != nil {
+			return fmt.Errorf("failed to name session: %w", err)
+		}
+	}
+
+	// Handle Ctrl+C gracefully. Signals are handled by this loop, which
+	// is the only one reading standard input, rather than by a goroutine
+	// racing it for the answers to the export prompt.
+	s.interrupts = make(chan os.Signal, 1)
+	signal.Notify(s.interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(s.interrupts)
 
-	reader := bufio.NewReader(os.Stdin)
-	
 	for {
+		if s.interrupted() {
+			fmt.Println()
+			s.promptForCLIGeneration()
+			return nil
+		}
+
 		// Read block-based input
-		codeBlock, shouldExit, err := s.readCodeBlock(reader)
+		codeBlock, shouldExit, err := s.readCodeBlock()
 		if err != nil {
-			if err == io.EOF {
+			if err == io.EOF || err == errInterrupted {
 				fmt.Println()
 				s.promptForCLIGeneration()
 				return nil
@@ -102,18 +323,22 @@ func (s *Shell) Run() error {
 		}
 
 		// Add to history
-		s.history = append(s.history, codeBlock)
+		s.addHistory(codeBlock)
 
 		// Try to compile/execute the code
-		if err := s.execute(codeBlock); err != nil {
-			fmt.Printf("Error: %v\n", err)
+		s.output.Reset()
+		start := time.Now()
+		err = s.execute(codeBlock)
+		s.last = lastBlock{evaluated: true, failed: err != nil, duration: time.Since(start)}
+		if err != nil {
+			fmt.Println(s.colorize(colorError, fmt.Sprintf("Error: %v", err)))
 			fmt.Println("Code not added to project. Fix and try again.")
 		} else {
 			// If successful, add to workspace
-			if err := s.workspace.AddCodeBlock(codeBlock); err != nil {
+			if err := s.workspace.AddCodeBlockWithOutput(codeBlock, s.output.String()); err != nil {
 				fmt.Printf("Warning: failed to save code: %v\n", err)
 			} else {
-				fmt.Println("✓ Code compiled and added to project")
+				fmt.Println(s.colorize(colorSuccess, "✓ Code compiled and added to project"))
 			}
 		}
 	}
@@ -121,20 +346,20 @@ func (s *Shell) Run() error {
 
 // readCodeBlock reads a multi-line code block
 // Press Enter for new lines, Ctrl+D (Cmd+D on Mac) to submit
-func (s *Shell) readCodeBlock(reader *bufio.Reader) (string, bool, error) {
-	fmt.Print("gosh> ")
+func (s *Shell) readCodeBlock() (string, bool, error) {
+	fmt.Print(s.prompt())
 	
 	// Check if stdin is a terminal
 	fd := int(os.Stdin.Fd())
 	isTerminal := term.IsTerminal(fd)
 	
-	if isTerminal {
+	if isTerminal && s.config.EditMode == config.EditRaw {
 		// Use raw mode for better control
 		return s.readCodeBlockRaw()
 	}
 	
 	// Fallback for non-terminal (pipes, redirects, etc.)
-	return s.readCodeBlockBuffered(reader)
+	return s.readCodeBlockBuffered()
 }
 
 // readCodeBlockRaw reads input using raw terminal mode with Ctrl+Enter detection
@@ -143,7 +368,7 @@ func (s *Shell) readCodeBlockRaw() (string, bool, error) {
 	oldState, err := term.MakeRaw(fd)
 	if err != nil {
 		// Fall back to buffered mode if raw mode fails
-		return s.readCodeBlockBuffered(bufio.NewReader(os.Stdin))
+		return s.readCodeBlockBuffered()
 	}
 	defer term.Restore(fd, oldState)
 	
@@ -185,12 +410,12 @@ func (s *Shell) readCodeBlockRaw() (string, bool, error) {
 			
 		case 10: // LF (Line Feed)
 			// Check if previous char was CR (regular Enter = CR+LF)
-			if prevChar == 13 {
+			if prevChar == 13 && !(s.submitOnBlankLine() && lineBuffer.Len() == 0) {
 				// This is regular Enter (CR+LF sequence) - add newline
 				buffer.WriteString(lineBuffer.String())
 				buffer.WriteString("\n")
 				lineBuffer.Reset()
-				fmt.Print("\r\n...  ")
+				fmt.Print("\r\n" + s.continuationPrompt())
 			} else {
 				// LF without CR = Ctrl+Enter on many Unix terminals
 				// Submit the block
@@ -209,7 +434,7 @@ func (s *Shell) readCodeBlockRaw() (string, bool, error) {
 					return result, false, nil
 				}
 				// Empty buffer - just show new prompt
-				fmt.Print("\r\ngosh> ")
+				fmt.Print("\r\n" + s.prompt())
 				lineBuffer.Reset()
 				buffer.Reset()
 			}
@@ -264,7 +489,7 @@ func (s *Shell) readCodeBlockRaw() (string, bool, error) {
 					return result, false, nil
 				}
 				// Empty buffer - show new prompt and process current character
-				fmt.Print("\r\ngosh> ")
+				fmt.Print("\r\n" + s.prompt())
 				lineBuffer.Reset()
 				buffer.Reset()
 			}
@@ -281,7 +506,7 @@ func (s *Shell) readCodeBlockRaw() (string, bool, error) {
 
 // readCodeBlockBuffered reads input using buffered reader (fallback mode)
 // Uses empty line to submit
-func (s *Shell) readCodeBlockBuffered(reader *bufio.Reader) (string, bool, error) {
+func (s *Shell) readCodeBlockBuffered() (string, bool, error) {
 	var lines []string
 	
 	firstLine := true
@@ -289,7 +514,7 @@ func (s *Shell) readCodeBlockBuffered(reader *bufio.Reader) (string, bool, error
 		var line string
 		var err error
 		
-		line, err = reader.ReadString('\n')
+		line, err = s.readLine()
 		if err != nil {
 			return "", false, err
 		}
@@ -302,11 +527,7 @@ func (s *Shell) readCodeBlockBuffered(reader *bufio.Reader) (string, bool, error
 		}
 		
 		// Check for special commands on first line
-		if firstLine && (strings.HasPrefix(line, "help") || 
-			strings.HasPrefix(line, "history") || 
-			strings.HasPrefix(line, "clear") || 
-			strings.HasPrefix(line, "workspace") ||
-			strings.HasPrefix(line, "reload")) {
+		if firstLine && isBuiltinCommand(line) {
 			return line, false, nil
 		}
 		
@@ -319,17 +540,48 @@ func (s *Shell) readCodeBlockBuffered(reader *bufio.Reader) (string, bool, error
 				return strings.Join(lines, "\n"), false, nil
 			}
 			// Empty input, start over
-			fmt.Print("gosh> ")
+			fmt.Print(s.prompt())
 			firstLine = true
 			continue
 		}
 		
 		// Add line to the block
 		lines = append(lines, line)
-		fmt.Print("...  ")
+		fmt.Print(s.continuationPrompt())
 	}
 }
 
+// builtinCommands lists the commands handled by handleBuiltinCommand
+var builtinCommands = map[string]bool{
+	"exit":       true,
+	"quit":       true,
+	"help":       true,
+	"history":    true,
+	"clear":      true,
+	"workspace":  true,
+	"reload":     true,
+	"export":     true,
+	"resume":     true,
+	"sessions":   true,
+	"name":       true,
+	"tag":        true,
+	"untag":      true,
+	"describe":   true,
+	"config":     true,
+	"undo":       true,
+	"drop":       true,
+	"blocks":     true,
+	"replace":    true,
+	"checkpoint": true,
+	"rollback":   true,
+	"fork":       true,
+}
+
+// isBuiltinCommand reports whether line invokes a shell built-in command
+func isBuiltinCommand(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) > 0 && builtinCommands[fields[0]]
+}
 
 // handleBuiltinCommand handles shell built-in commands
 func (s *Shell) handleBuiltinCommand(input string) bool {
@@ -367,11 +619,103 @@ func (s *Shell) handleBuiltinCommand(input string) bool {
 		fmt.Printf("Workspace directory: %s\n", s.workspace.Path())
 		fmt.Printf("Internal directory: %s\n", s.workspace.InternalPath())
 		fmt.Printf("Session ID: %s\n", s.workspace.SessionID())
+		if name := s.workspace.Meta().Name; name != "" {
+			fmt.Printf("Session name: %s\n", name)
+		}
+		if s.module != nil {
+			fmt.Printf("Go module: %s (%s)\n", s.module.Path, s.module.Dir)
+		}
+		if _, err := os.Stat(s.workspace.RCPath()); err == nil && !s.options.NoRC {
+			fmt.Printf("Startup file: %s\n", s.workspace.RCPath())
+		}
+		return true
+
+	case "export":
+		s.handleExport(parts[1:])
+		return true
+
+	case "config":
+		s.showConfig()
+		return true
+
+	case "undo":
+		s.undoBlock()
+		return true
+
+	case "drop", "replace":
+		if len(parts) != 2 {
+			fmt.Printf("Usage: %s <n>\n", command)
+			return true
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			fmt.Printf("Invalid block number %q\n", parts[1])
+			return true
+		}
+		if command == "drop" {
+			s.dropBlock(n)
+		} else {
+			s.replaceBlock(n)
+		}
+		return true
+
+	case "blocks":
+		s.printBlocks()
+		return true
+
+	case "checkpoint":
+		if len(parts) > 2 {
+			fmt.Println("Usage: checkpoint [name]")
+			return true
+		}
+		if len(parts) == 1 {
+			s.printCheckpoints()
+		} else {
+			s.setCheckpoint(parts[1])
+		}
+		return true
+
+	case "rollback":
+		if len(parts) != 2 {
+			fmt.Println("Usage: rollback <name>")
+			return true
+		}
+		s.rollback(parts[1])
+		return true
+
+	case "resume":
+		id := ""
+		if len(parts) > 1 {
+			id = parts[1]
+		}
+		if err := s.resumeSession(id); err != nil {
+			fmt.Printf("Error resuming session: %v\n", err)
+		}
+		return true
+
+	case "sessions":
+		s.handleSessions(parts[1:])
+		return true
+
+	case "fork":
+		if len(parts) != 2 {
+			fmt.Println("Usage: fork <name>")
+			return true
+		}
+		s.forkSession(parts[1])
+		return true
+
+	case "name", "tag", "untag", "describe":
+		s.handleSessionMeta(command, strings.TrimSpace(strings.TrimPrefix(input, command)))
 		return true
 
 	case "reload":
 		// Reload workspace - recreate interpreter
-		if err := s.reloadWorkspace(); err != nil {
+		reload := s.reloadWorkspace
+		if len(parts) > 1 && parts[1] == "--all" {
+			reload = s.reloadAll
+		}
+		if err := reload(); err != nil {
 			fmt.Printf("Error reloading workspace: %v\n", err)
 		} else {
 			fmt.Println("Workspace reloaded successfully")
@@ -383,16 +727,21 @@ func (s *Shell) handleBuiltinCommand(input string) bool {
 	}
 }
 
-// promptForCLIGeneration prompts the user to save session as a Cobra CLI tool
+// promptForCLIGeneration prompts the user to save session as a Cobra CLI tool.
+// Answers are read from the same buffered reader as the code blocks, so that
+// piped input is not lost to a second buffer.
 func (s *Shell) promptForCLIGeneration() {
+	if !s.config.ExitPrompt {
+		fmt.Println("Exiting gosh...")
+		return
+	}
 	if len(s.workspace.GetCodeBlocks()) == 0 {
 		fmt.Println("No code blocks to save. Exiting...")
 		return
 	}
 
 	fmt.Print("\nWould you like to save this session as a CLI tool? (y/n): ")
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+	response, err := s.readLine()
 	if err != nil {
 		fmt.Println("Exiting...")
 		return
@@ -401,7 +750,7 @@ func (s *Shell) promptForCLIGeneration() {
 
 	if response == "y" || response == "yes" {
 		fmt.Print("Enter CLI tool name: ")
-		name, err := reader.ReadString('\n')
+		name, err := s.readLine()
 		if err != nil {
 			fmt.Println("Exiting...")
 			return
@@ -409,36 +758,169 @@ func (s *Shell) promptForCLIGeneration() {
 		name = strings.TrimSpace(name)
 
 		if name != "" {
-			if err := s.workspace.GenerateCobraCLI(name); err != nil {
-				fmt.Printf("Error generating CLI tool: %v\n", err)
-			} else {
-				fmt.Printf("✓ CLI tool '%s' generated successfully!\n", name)
-				fmt.Printf("  Location: %s/cmd/%s/\n", s.workspace.Path(), name)
-				fmt.Printf("  To build: cd %s/cmd/%s && go build\n", s.workspace.Path(), name)
+			fmt.Print("Flavor: [c]obra or [s]td (standard library only) [c]: ")
+			flavor, err := s.readLine()
+			if err != nil {
+				fmt.Println("Exiting...")
+				return
+			}
+
+			var opts workspace.CLIOptions
+			switch strings.TrimSpace(strings.ToLower(flavor)) {
+			case "s", "std":
+				opts.Flavor = workspace.StdFlavor
+			default:
+				opts.Flavor = workspace.CobraFlavor
+			}
+
+			fmt.Print("Dependencies: [w]orkspace go.mod, [s]tandalone go.mod, [v]endored standalone (offline) [w]: ")
+			mode, err := s.readLine()
+			if err != nil {
+				fmt.Println("Exiting...")
+				return
+			}
+
+			switch strings.TrimSpace(strings.ToLower(mode)) {
+			case "s", "standalone":
+				opts.Standalone = true
+			case "v", "vendor", "vendored":
+				opts.Standalone = true
+				opts.Vendor = true
+			}
+
+			flags, err := s.promptForFlags()
+			if err != nil {
+				fmt.Println("Exiting...")
+				return
 			}
+			opts.Flags = flags
+
+			s.generateCLI(name, opts)
 		}
 	}
 
 	fmt.Println("Exiting gosh...")
 }
 
+// promptForFlags lets the user pick session variables to expose as flags of
+// the generated CLI tool. Variables marked with //gosh:flag are always exposed.
+func (s *Shell) promptForFlags() ([]string, error) {
+	candidates := s.workspace.FlagCandidates()
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	fmt.Println("Session variables that can become flags:")
+	for i, c := range candidates {
+		marker := ""
+		if c.Marked {
+			marker = "  (//gosh:flag)"
+		}
+		fmt.Printf("%4d  %s %s = %s%s\n", i+1, c.Name, c.Type, c.Default, marker)
+	}
+	fmt.Print("Variables to expose as flags (numbers separated by commas, empty for marked only): ")
+	response, err := s.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, field := range strings.FieldsFunc(response, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' || r == '\r' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(candidates) {
+			fmt.Printf("Ignoring invalid selection: %s\n", field)
+			continue
+		}
+		names = append(names, candidates[n-1].Name)
+	}
+	return names, nil
+}
+
 // execute runs the given Go code
 func (s *Shell) execute(code string) error {
-	_, err := s.interpreter.Eval(code)
+	ctx, cancel := s.cancelOnInterrupt(context.Background())
+	defer cancel()
+	if timeout := time.Duration(s.config.Timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	_, err := s.interpreter.EvalWithContext(ctx, code)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("evaluation timed out after %s", time.Duration(s.config.Timeout))
+	}
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("evaluation interrupted")
+	}
+	if err != nil {
+		if hint := s.importHint(code); hint != "" {
+			return fmt.Errorf("%w\n%s", err, hint)
+		}
+	}
 	return err
 }
 
-// reloadWorkspace reloads workspace by creating a new interpreter
+// reloadWorkspace reloads workspace by creating a new interpreter. Only
+// imports, declarations and statements without side effects are evaluated
+// again; statements with side effects are re-run when their block is marked
+// //gosh:rerun or the user agrees. Steps using a name a skipped step
+// declares or assigns are skipped as well, since it is undefined or stale.
 func (s *Shell) reloadWorkspace() error {
 	// Create a new interpreter
-	i := interp.New(interp.Options{})
-	if err := i.Use(stdlib.Symbols); err != nil {
-		return fmt.Errorf("failed to load standard library: %w", err)
+	i, err := s.newInterpreter()
+	if err != nil {
+		return err
 	}
 
-	// Pre-import commonly used packages
-	if _, err := i.Eval(`import "fmt"`); err != nil {
-		return fmt.Errorf("failed to import fmt: %w", err)
+	skipped, dependent := 0, 0
+	stale := make(map[string]bool)
+	policy := rerunAsk
+	for n, block := range workspace.PlanReload(s.workspace.GetCodeBlocks()) {
+		rerun := block.Rerun
+		if !rerun && !block.Once && block.HasSideEffects() {
+			rerun, policy = s.askRerun(n+1, block, policy)
+		}
+		for _, step := range block.Steps {
+			switch {
+			case step.SideEffects && !rerun:
+				skipped++
+			case slices.ContainsFunc(step.Uses, func(name string) bool { return stale[name] }):
+				dependent++
+			default:
+				if _, err := i.Eval(step.Code); err != nil {
+					return fmt.Errorf("failed to evaluate block %d: %w", n+1, err)
+				}
+				// A name evaluated again is up to date
+				for _, name := range step.Defines {
+					delete(stale, name)
+				}
+				continue
+			}
+			for _, name := range step.Defines {
+				stale[name] = true
+			}
+		}
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipped %d statements with side effects", skipped)
+		if dependent > 0 {
+			fmt.Printf(" and %d using their results", dependent)
+		}
+		fmt.Println()
+	}
+
+	s.interpreter = i
+	return nil
+}
+
+// reloadAll reloads workspace by creating a new interpreter and evaluating
+// every block again, side effects included
+func (s *Shell) reloadAll() error {
+	// Create a new interpreter
+	i, err := s.newInterpreter()
+	if err != nil {
+		return err
 	}
 
 	// Re-execute all code blocks
@@ -452,6 +934,47 @@ func (s *Shell) reloadWorkspace() error {
 	return nil
 }
 
+// resumeSession replays the blocks of a saved session, addressed by ID or
+// name, or the most recent one when ref is empty, and continues it. The
+// current session is kept when a block fails to replay.
+func (s *Shell) resumeSession(ref string) error {
+	var id string
+	var err error
+	if ref == "" {
+		id, err = s.workspace.LatestSessionID()
+	} else {
+		id, err = s.workspace.ResolveSession(ref)
+	}
+	if err != nil {
+		return err
+	}
+	// Check before replaying the blocks, which runs their side effects
+	if s.workspace.SessionInUse(id) {
+		return fmt.Errorf("session %s is in use by another gosh instance", id)
+	}
+
+	blocks, err := s.workspace.LoadSession(id)
+	if err != nil {
+		return err
+	}
+
+	// Replay the blocks, recording their output again since session files
+	// only hold the code
+	i, outputs, err := s.replay(blocks)
+	if err != nil {
+		return fmt.Errorf("failed to replay session %s: %w", id, err)
+	}
+
+	if err := s.workspace.Resume(id, blocks, outputs); err != nil {
+		return err
+	}
+	s.interpreter = i
+	s.addHistory(blocks...)
+
+	fmt.Printf("✓ Resumed session %s (%d blocks)\n", id, len(blocks))
+	return nil
+}
+
 // printHelp displays help information
 func (s *Shell) printHelp() {
 	// Detect OS for key combination display
@@ -465,13 +988,39 @@ func (s *Shell) printHelp() {
 	fmt.Println("  history     - Show command history")
 	fmt.Println("  clear       - Clear history and workspace")
 	fmt.Println("  workspace   - Show workspace information")
-	fmt.Println("  reload      - Reload workspace code")
+	fmt.Println("  reload [--all] - Reload workspace code, asking before re-running side effects")
+	fmt.Println("              (--all re-runs every block as is)")
+	fmt.Println("  resume [id|name] - Replay and continue a saved session (the latest by default)")
+	fmt.Println("  sessions [list | show <id> | rm <id> | prune --older-than=30d]")
+	fmt.Println("              - List, inspect and delete saved sessions (by ID or name)")
+	fmt.Println("  name <name> - Name the session")
+	fmt.Println("  tag <tag>... / untag <tag>...")
+	fmt.Println("              - Attach or detach session tags")
+	fmt.Println("  describe <text> - Describe the session")
+	fmt.Println("  fork <name> - Continue in a copy of the session named name, keeping the original")
+	fmt.Println("  blocks      - List the blocks of the session with their numbers")
+	fmt.Println("  replace <n> - Edit block n ($EDITOR or prompt) and replay the session")
+	fmt.Println("  undo        - Remove the last block from the session")
+	fmt.Println("  drop <n>    - Remove block n from the session")
+	fmt.Println("  checkpoint [name] - Record the current block as a checkpoint, or list checkpoints")
+	fmt.Println("  rollback <name> - Remove the blocks added since a checkpoint and replay the session")
+	fmt.Println("  config      - Show the effective settings and where they come from")
+	fmt.Println("  export cli <name> [--flavor=cobra|std] [--standalone] [--vendor] [--flags=a,b] [--out=dir] [--build]")
+	fmt.Println("              - Generate a CLI tool from the session")
+	fmt.Println("  export pkg <name> [--out=dir]")
+	fmt.Println("              - Export session functions and types as a library package")
+	fmt.Println("  export test <name> [--out=dir]")
+	fmt.Println("              - Generate a golden test replaying the session output")
 	fmt.Println("  exit/quit   - Exit the shell (prompts to save as CLI tool)")
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  - Type or paste multi-line Go code")
 	fmt.Println("  - Press Enter to add new lines within your code block")
-	fmt.Printf("  - Press %s+Enter to execute the code block\n", ctrlKey)
+	if s.submitOnBlankLine() {
+		fmt.Println("  - Press Enter on an empty line to execute the code block")
+	} else {
+		fmt.Printf("  - Press %s+Enter to execute the code block\n", ctrlKey)
+	}
 	fmt.Println("  - On exit, you can save your session as a Cobra-based CLI tool")
 	fmt.Println()
 	fmt.Println("Examples:")
@@ -1,10 +1,14 @@
 package shell
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestNew(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	sh, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create shell: %v", err)
@@ -24,6 +28,8 @@ func TestNew(t *testing.T) {
 }
 
 func TestExecute(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	sh, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create shell: %v", err)
@@ -62,6 +68,8 @@ func TestExecute(t *testing.T) {
 }
 
 func TestHandleBuiltinCommand(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	sh, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create shell: %v", err)
@@ -97,6 +105,31 @@ func TestHandleBuiltinCommand(t *testing.T) {
 			input:     "reload",
 			isBuiltin: true,
 		},
+		{
+			name:      "Export command",
+			input:     "export",
+			isBuiltin: true,
+		},
+		{
+			name:      "Resume command",
+			input:     "resume does_not_exist",
+			isBuiltin: true,
+		},
+		{
+			name:      "Sessions command",
+			input:     "sessions show does_not_exist",
+			isBuiltin: true,
+		},
+		{
+			name:      "Tag command",
+			input:     "tag",
+			isBuiltin: true,
+		},
+		{
+			name:      "Config command",
+			input:     "config",
+			isBuiltin: true,
+		},
 		{
 			name:      "Not a builtin command",
 			input:     "x := 42",
@@ -121,6 +154,8 @@ func TestHandleBuiltinCommand(t *testing.T) {
 }
 
 func TestReloadWorkspace(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	sh, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create shell: %v", err)
@@ -142,3 +177,133 @@ func TestReloadWorkspace(t *testing.T) {
 		t.Error("Interpreter is nil after reload")
 	}
 }
+
+func TestResumeSession(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	previous, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	for _, block := range []string{`greeting := "hello"`, `fmt.Println(greeting)`} {
+		if err := previous.workspace.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+	id := previous.workspace.SessionID()
+	if err := previous.workspace.Close(); err != nil {
+		t.Fatalf("Failed to close workspace: %v", err)
+	}
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	if err := sh.resumeSession(id); err != nil {
+		t.Fatalf("Failed to resume session: %v", err)
+	}
+
+	if got := sh.workspace.SessionID(); got != id {
+		t.Errorf("Expected session %s, got %s", id, got)
+	}
+	if outputs := sh.workspace.GetBlockOutputs(); len(outputs) != 2 || outputs[1] != "hello\n" {
+		t.Errorf("Replayed outputs should be recorded, got %q", outputs)
+	}
+
+	// Replayed declarations are available to new blocks
+	if err := sh.execute(`greeting += "!"`); err != nil {
+		t.Errorf("Resumed variables should be defined: %v", err)
+	}
+
+	if err := sh.resumeSession("does_not_exist"); err == nil {
+		t.Error("Expected an error resuming an unknown session")
+	}
+	if got := sh.workspace.SessionID(); got != id {
+		t.Errorf("A failed resume should keep the session, got %s", got)
+	}
+}
+
+func TestModuleImport(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                  "module example.com/demo\n\ngo 1.25\n",
+		"greet/greet.go":          "package greet\n\nimport \"example.com/demo/internal/names\"\n\nfunc Hello(name string) string { return \"Hello, \" + names.Clean(name) }\n",
+		"internal/names/names.go": "package names\n\nimport \"strings\"\n\nfunc Clean(name string) string { return strings.TrimSpace(name) }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	t.Chdir(filepath.Join(dir, "greet"))
+
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	if sh.module == nil || sh.module.Path != "example.com/demo" {
+		t.Fatalf("Expected module example.com/demo, got %+v", sh.module)
+	}
+
+	if err := sh.execute(`import "example.com/demo/greet"`); err != nil {
+		t.Fatalf("Failed to import module package: %v", err)
+	}
+	sh.output.Reset()
+	if err := sh.execute(`fmt.Print(greet.Hello(" gopher "))`); err != nil {
+		t.Fatalf("Failed to call module package: %v", err)
+	}
+	if got := sh.output.String(); got != "Hello, gopher" {
+		t.Errorf("Unexpected output %q", got)
+	}
+
+	sh, err = NewWithOptions(Options{NoModule: true})
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	if err := sh.execute(`import "example.com/demo/greet"`); err == nil {
+		t.Error("Module packages should not be importable with NoModule")
+	}
+}
+
+func TestStartupFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("GOSH_HOME", home)
+
+	rc := "import \"strings\"\n\nfunc shout(s string) string { return strings.ToUpper(s) }\n\nundefinedHelper()\n"
+	if err := os.WriteFile(filepath.Join(home, "goshrc.go"), []byte(rc), 0644); err != nil {
+		t.Fatalf("Failed to write startup file: %v", err)
+	}
+
+	// The failing block is reported without preventing the shell from starting
+	sh, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	if err := sh.execute(`fmt.Print(shout("hi"))`); err != nil {
+		t.Fatalf("Startup helpers should be defined: %v", err)
+	}
+
+	if err := sh.reloadWorkspace(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if err := sh.execute(`_ = shout("again")`); err != nil {
+		t.Errorf("Startup helpers should be defined after reload: %v", err)
+	}
+	if len(sh.workspace.GetCodeBlocks()) != 0 {
+		t.Error("Startup blocks should not be saved in the session")
+	}
+
+	sh, err = NewWithOptions(Options{NoRC: true})
+	if err != nil {
+		t.Fatalf("Failed to create shell: %v", err)
+	}
+	if err := sh.execute(`_ = shout("hi")`); err == nil {
+		t.Error("Startup file should be skipped with NoRC")
+	}
+}
@@ -0,0 +1,245 @@
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// GoshModule is the module path of gosh, required by custom gosh binaries
+const GoshModule = "github.com/Napolitain/gosh"
+
+// WorkspaceModule is the module path of the workspace, under which the
+// packages exported from sessions are imported
+const WorkspaceModule = "gosh"
+
+const (
+	bundlesDir       = "bundles"
+	bundleMainDir    = "bundles/gosh"
+	bundleExtractDir = "bundles/extract"
+	bundleBinDir     = "bin"
+)
+
+// bundlesSource declares the symbol table filled by the extracted files,
+// which all belong to the bundles package
+const bundlesSource = `// Package bundles holds the package symbols extracted by gosh extract
+package bundles
+
+import "reflect"
+
+// Symbols maps the extracted packages to their symbols
+var Symbols = map[string]map[string]reflect.Value{}
+`
+
+// bundleMainSource is the main package of the custom gosh binary
+const bundleMainSource = `// Command gosh is gosh with the packages extracted in the workspace
+// precompiled. It is generated by gosh extract.
+package main
+
+import (
+	"github.com/Napolitain/gosh/app"
+
+	"gosh/bundles"
+)
+
+func main() {
+	app.Register(bundles.Symbols)
+	app.Main()
+}
+`
+
+// bundleExtractSource is the command printing the symbol table of a package.
+// The extractor resolves packages from the current directory, so it runs in
+// the workspace module requiring them rather than in gosh.
+const bundleExtractSource = `// Command extract prints the yaegi symbol table of a package of the
+// workspace module. It is generated by gosh extract.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/traefik/yaegi/extract"
+)
+
+func main() {
+	extractor := extract.Extractor{Dest: "bundles"}
+	if _, err := extractor.Extract(os.Args[1], "", os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
+
+// BundleOptions configures the custom gosh binary built by ExtractBundles
+type BundleOptions struct {
+	// GoshVersion is the version of gosh the custom binary is built from
+	GoshVersion string
+	// GoshSource is a local gosh checkout used instead of GoshVersion
+	GoshSource string
+	// NoBuild only generates the symbol tables
+	NoBuild bool
+}
+
+// ExtractBundles generates the yaegi symbol tables of the given packages,
+// which may carry a @version, into the bundles directory of the workspace,
+// then builds a custom gosh binary with every extracted package precompiled.
+// It returns the path of the binary, which is empty with NoBuild.
+func (w *Workspace) ExtractBundles(packages []string, opts BundleOptions) (string, error) {
+	unlock, err := w.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	dir := filepath.Join(w.rootPath, bundlesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create bundles directory: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "bundles.go"), []byte(bundlesSource), 0644); err != nil {
+		return "", fmt.Errorf("failed to write bundles package: %w", err)
+	}
+	if err := w.writeExtractCommand(); err != nil {
+		return "", err
+	}
+
+	for _, pkg := range packages {
+		importPath, _, _ := strings.Cut(pkg, "@")
+		if !isStdlibPackage(importPath) {
+			if err := runGo(w.rootPath, "get", pkg); err != nil {
+				return "", fmt.Errorf("failed to download %s: %w", pkg, err)
+			}
+		}
+		if err := w.extractSymbols(importPath); err != nil {
+			return "", err
+		}
+	}
+
+	if opts.NoBuild {
+		return "", nil
+	}
+	return w.buildBundleBinary(opts)
+}
+
+// writeExtractCommand writes the command extracting symbols to the
+// workspace and makes its module require yaegi
+func (w *Workspace) writeExtractCommand() error {
+	dir := filepath.Join(w.rootPath, filepath.FromSlash(bundleExtractDir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", bundleExtractDir, err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "main.go"), []byte(bundleExtractSource), 0644); err != nil {
+		return fmt.Errorf("failed to write extract command: %w", err)
+	}
+	if err := addRequirements(w.rootPath, yaegiRequirements, yaegiSums); err != nil {
+		return fmt.Errorf("failed to require yaegi: %w", err)
+	}
+	return nil
+}
+
+// extractSymbols writes the symbol table of a package to the bundles
+// directory, named like yaegi extract names it
+func (w *Workspace) extractSymbols(importPath string) error {
+	symbols, err := goOutput(w.rootPath, "run", "./"+bundleExtractDir, importPath)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", importPath, err)
+	}
+
+	name := strings.NewReplacer("/", "-", ".", "_", "~", "_").Replace(importPath) + ".go"
+	if err := writeFileAtomic(filepath.Join(w.rootPath, bundlesDir, name), symbols, 0644); err != nil {
+		return fmt.Errorf("failed to write symbols of %s: %w", importPath, err)
+	}
+	return nil
+}
+
+// buildBundleBinary builds the custom gosh binary of the workspace
+func (w *Workspace) buildBundleBinary(opts BundleOptions) (string, error) {
+	mainDir := filepath.Join(w.rootPath, filepath.FromSlash(bundleMainDir))
+	if err := os.MkdirAll(mainDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", bundleMainDir, err)
+	}
+	if err := writeFileAtomic(filepath.Join(mainDir, "main.go"), []byte(bundleMainSource), 0644); err != nil {
+		return "", fmt.Errorf("failed to write custom gosh main package: %w", err)
+	}
+
+	switch {
+	case opts.GoshSource != "":
+		source, err := filepath.Abs(opts.GoshSource)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve gosh source: %w", err)
+		}
+		if err := runGo(w.rootPath, "mod", "edit", "-replace", GoshModule+"="+source); err != nil {
+			return "", err
+		}
+		if err := runGo(w.rootPath, "get", GoshModule+"/app"); err != nil {
+			return "", err
+		}
+	case opts.GoshVersion != "":
+		if err := runGo(w.rootPath, "get", GoshModule+"/app@"+opts.GoshVersion); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("cannot tell which gosh version to build, use a released gosh or pass a local gosh checkout")
+	}
+
+	binary := w.bundleBinaryPath()
+	if err := runGo(w.rootPath, "build", "-o", binary, "./"+bundleMainDir); err != nil {
+		return "", err
+	}
+	return binary, nil
+}
+
+// bundleBinaryPath returns the path of the custom gosh binary of the workspace
+func (w *Workspace) bundleBinaryPath() string {
+	name := "gosh"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(w.rootPath, bundleBinDir, name)
+}
+
+// BundleBinary returns the path of the custom gosh binary built by
+// ExtractBundles, if any
+func (w *Workspace) BundleBinary() (string, bool) {
+	path := w.bundleBinaryPath()
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// isStdlibPackage reports whether an import path belongs to the standard
+// library, whose paths have no domain name
+func isStdlibPackage(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// runGo runs a go command in dir
+func runGo(dir string, args ...string) error {
+	_, err := goOutput(dir, args...)
+	return err
+}
+
+// goOutput runs a go command in dir and returns its standard output
+func goOutput(dir string, args ...string) ([]byte, error) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return nil, fmt.Errorf("go toolchain not found: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(goBin, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off")
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go %s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
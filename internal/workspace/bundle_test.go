@@ -0,0 +1,59 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractBundles(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if _, ok := ws.BundleBinary(); ok {
+		t.Error("Expected no custom binary in a new workspace")
+	}
+
+	binary, err := ws.ExtractBundles([]string{"text/tabwriter"}, BundleOptions{NoBuild: true})
+	if err != nil {
+		t.Fatalf("Failed to extract symbols: %v", err)
+	}
+	if binary != "" {
+		t.Errorf("Expected no binary with NoBuild, got %s", binary)
+	}
+
+	content, err := os.ReadFile(filepath.Join(ws.Path(), "bundles", "text-tabwriter.go"))
+	if err != nil {
+		t.Fatalf("Failed to read extracted symbols: %v", err)
+	}
+	for _, want := range []string{"package bundles", `Symbols["text/tabwriter/tabwriter"]`, `"NewWriter"`} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected %q in extracted symbols", want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(ws.Path(), "bundles", "bundles.go")); err != nil {
+		t.Errorf("Expected the bundles package declaration: %v", err)
+	}
+
+	if _, err := ws.ExtractBundles([]string{"text/missing"}, BundleOptions{NoBuild: true}); err == nil {
+		t.Error("Expected an error extracting a missing package")
+	}
+}
+
+func TestIsStdlibPackage(t *testing.T) {
+	tests := map[string]bool{
+		"fmt":                   true,
+		"net/http":              true,
+		"golang.org/x/sys/unix": false,
+		"example.com":           false,
+	}
+	for path, want := range tests {
+		if got := isStdlibPackage(path); got != want {
+			t.Errorf("isStdlibPackage(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package workspace
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// SetCheckpoint records the current number of blocks of the session under a
+// name, replacing any checkpoint with the same name
+func (w *Workspace) SetCheckpoint(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid checkpoint name %q", name)
+	}
+	if w.meta.Checkpoints == nil {
+		w.meta.Checkpoints = make(map[string]int)
+	}
+	w.meta.Checkpoints[name] = len(w.codeBlocks)
+	return w.writeMeta()
+}
+
+// Checkpoint returns the number of blocks the session had when the named
+// checkpoint was recorded
+func (w *Workspace) Checkpoint(name string) (int, error) {
+	n, ok := w.meta.Checkpoints[name]
+	if !ok {
+		return 0, fmt.Errorf("no checkpoint named %q", name)
+	}
+	return n, nil
+}
+
+// RemoveCodeBlock removes block n, numbered from 1, from the session, given
+// the standard output each remaining block produced when replayed, and
+// rewrites the session file. Checkpoints recorded after the block move back
+// by one, so that they still follow the same blocks.
+func (w *Workspace) RemoveCodeBlock(n int, outputs []string) error {
+	if n < 1 || n > len(w.codeBlocks) {
+		return fmt.Errorf("no block %d, the session has %d blocks", n, len(w.codeBlocks))
+	}
+
+	// Shift before the blocks are set, which drops the checkpoints past the
+	// end of the session
+	checkpoints := w.meta.Checkpoints
+	shifted := maps.Clone(checkpoints)
+	for name, count := range shifted {
+		if count >= n {
+			shifted[name] = count - 1
+		}
+	}
+	w.meta.Checkpoints = shifted
+
+	blocks := w.GetCodeBlocks()
+	blocks = append(blocks[:n-1], blocks[n:]...)
+	if err := w.SetCodeBlocks(blocks, outputs); err != nil {
+		w.meta.Checkpoints = checkpoints
+		return err
+	}
+	if maps.Equal(checkpoints, w.meta.Checkpoints) {
+		return nil
+	}
+	return w.writeMeta()
+}
+
+// pruneCheckpoints removes the checkpoints past the end of the session, left
+// behind when blocks are removed
+func (w *Workspace) pruneCheckpoints() error {
+	pruned := false
+	for name, n := range w.meta.Checkpoints {
+		if n > len(w.codeBlocks) {
+			delete(w.meta.Checkpoints, name)
+			pruned = true
+		}
+	}
+	if !pruned {
+		return nil
+	}
+	return w.writeMeta()
+}
@@ -0,0 +1,94 @@
+package workspace
+
+import "testing"
+
+func TestCheckpoints(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if err := ws.AddCodeBlock("a := 1"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if err := ws.SetCheckpoint("setup"); err != nil {
+		t.Fatalf("Failed to set checkpoint: %v", err)
+	}
+	if err := ws.AddCodeBlock("b := 2"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if err := ws.SetCheckpoint("both"); err != nil {
+		t.Fatalf("Failed to set checkpoint: %v", err)
+	}
+	if err := ws.SetCheckpoint("bad name"); err == nil {
+		t.Error("Expected an error for a checkpoint name with spaces")
+	}
+
+	// Checkpoints are stored in the session metadata
+	meta, err := ws.readMeta(ws.SessionID())
+	if err != nil {
+		t.Fatalf("Failed to read metadata: %v", err)
+	}
+	if meta.Checkpoints["setup"] != 1 || meta.Checkpoints["both"] != 2 {
+		t.Errorf("Unexpected checkpoints %v", meta.Checkpoints)
+	}
+	if _, err := ws.Checkpoint("missing"); err == nil {
+		t.Error("Expected an error for an unknown checkpoint")
+	}
+
+	// Checkpoints past the end of the session are dropped with the blocks
+	if err := ws.SetCodeBlocks([]string{"a := 1"}, []string{""}); err != nil {
+		t.Fatalf("Failed to set code blocks: %v", err)
+	}
+	if n, err := ws.Checkpoint("setup"); err != nil || n != 1 {
+		t.Errorf("Expected checkpoint setup at block 1, got %d, %v", n, err)
+	}
+	if _, err := ws.Checkpoint("both"); err == nil {
+		t.Error("Checkpoint both should be removed with block 2")
+	}
+	if meta, _ := ws.readMeta(ws.SessionID()); len(meta.Checkpoints) != 1 {
+		t.Errorf("Expected the pruned checkpoints to be saved, got %v", meta.Checkpoints)
+	}
+}
+
+func TestRemoveCodeBlockShiftsCheckpoints(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	for i, block := range []string{"a := 1", "b := 2", "c := 3"} {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+		if err := ws.SetCheckpoint(string(rune('x' + i))); err != nil {
+			t.Fatalf("Failed to set checkpoint: %v", err)
+		}
+	}
+
+	if err := ws.RemoveCodeBlock(2, []string{"", ""}); err != nil {
+		t.Fatalf("Failed to remove block: %v", err)
+	}
+	if blocks := ws.GetCodeBlocks(); len(blocks) != 2 || blocks[1] != "c := 3" {
+		t.Fatalf("Unexpected blocks %q", blocks)
+	}
+
+	// Checkpoints keep following the same blocks, the one after the removed
+	// block included
+	want := map[string]int{"x": 1, "y": 1, "z": 2}
+	for name, n := range want {
+		if got, err := ws.Checkpoint(name); err != nil || got != n {
+			t.Errorf("Expected checkpoint %s at block %d, got %d, %v", name, n, got, err)
+		}
+	}
+	meta, err := ws.readMeta(ws.SessionID())
+	if err != nil || meta.Checkpoints["z"] != 2 {
+		t.Errorf("Expected the shifted checkpoints to be saved, got %v, %v", meta.Checkpoints, err)
+	}
+
+	if err := ws.RemoveCodeBlock(3, nil); err == nil {
+		t.Error("Expected an error removing a missing block")
+	}
+}
@@ -0,0 +1,700 @@
+package workspace
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// flagMarker marks a session variable that should become a CLI flag
+const flagMarker = "//gosh:flag"
+
+// Flavor selects the command-line framework used by a generated CLI tool
+type Flavor string
+
+const (
+	// CobraFlavor generates a tool based on github.com/spf13/cobra
+	CobraFlavor Flavor = "cobra"
+	// StdFlavor generates a dependency-free tool based on the flag package
+	StdFlavor Flavor = "std"
+)
+
+// ParseFlavor returns the flavor with the given name, defaulting to Cobra
+// when name is empty
+func ParseFlavor(name string) (Flavor, error) {
+	switch Flavor(strings.ToLower(name)) {
+	case "", CobraFlavor:
+		return CobraFlavor, nil
+	case StdFlavor:
+		return StdFlavor, nil
+	}
+	return "", fmt.Errorf("unknown CLI flavor %q (expected %q or %q)", name, CobraFlavor, StdFlavor)
+}
+
+// CLIOptions controls how a CLI tool is generated from the session
+type CLIOptions struct {
+	// Flavor selects the command-line framework, Cobra when empty
+	Flavor Flavor
+
+	// Standalone writes a dedicated go.mod and go.sum in the tool directory
+	// instead of adding the requirements to the workspace go.mod
+	Standalone bool
+
+	// Vendor copies the dependencies from the local module cache into a
+	// vendor directory, so the tool can be built offline
+	Vendor bool
+
+	// Flags lists session variables to expose as command-line flags, in
+	// addition to those marked with a //gosh:flag comment
+	Flags []string
+
+	// Dir is the directory the tool is written to, CLIPath(name) when empty.
+	// A tool written outside the workspace always gets its own go.mod.
+	Dir string
+
+	// Build runs go build on the generated tool once it passed the type
+	// check, when a Go toolchain is installed
+	Build bool
+}
+
+// FlagCandidate is a top-level session variable initialized with a literal
+// of a basic type, which can be exposed as a flag of a generated CLI tool
+type FlagCandidate struct {
+	Name    string // variable name
+	Type    string // Go type of the variable
+	Default string // literal used as default value
+	Marked  bool   // annotated with //gosh:flag
+}
+
+// cliFlag is a session variable exposed as a command-line flag
+type cliFlag struct {
+	FlagCandidate
+	usage string
+	item  sourceItem
+}
+
+// usageText returns the help text of the flag
+func (f cliFlag) usageText() string {
+	if f.usage == "" {
+		return "value of session variable " + f.Name
+	}
+	return f.usage
+}
+
+// cliParam is a parameter of a session function exposed as a subcommand
+type cliParam struct {
+	name string
+	typ  string
+}
+
+// cliCommand is an exported session function exposed as a subcommand
+type cliCommand struct {
+	funcName     string
+	params       []cliParam
+	returnsError bool
+	short        string
+}
+
+// cliSource is the session code rearranged into a Go program
+type cliSource struct {
+	imports  []string
+	decls    []sourceItem
+	stmts    []sourceItem
+	flags    []cliFlag
+	commands []cliCommand
+}
+
+// flagTypes maps the supported variable types to their pflag setter suffix
+var flagTypes = map[string]string{
+	"string":  "String",
+	"bool":    "Bool",
+	"int":     "Int",
+	"int64":   "Int64",
+	"uint":    "Uint",
+	"uint64":  "Uint64",
+	"float64": "Float64",
+}
+
+// GenerateCobraCLI generates a Cobra-based CLI tool from the session code
+// using the workspace go.mod
+func (w *Workspace) GenerateCobraCLI(name string) error {
+	return w.GenerateCLI(name, CLIOptions{Flavor: CobraFlavor})
+}
+
+// GenerateStdCLI generates a CLI tool from the session code that only
+// depends on the standard library
+func (w *Workspace) GenerateStdCLI(name string) error {
+	return w.GenerateCLI(name, CLIOptions{Flavor: StdFlavor})
+}
+
+// GenerateCLI generates a CLI tool of the requested flavor from the session
+// code and records its dependencies according to opts. The generated code is
+// type-checked, and a *CompileError locating the failures in the session
+// blocks is returned when it does not compile; the files are still written
+// so they can be inspected. The *CompileError only holds warnings when some
+// imports could not be resolved to check the code using them, unless
+// opts.Build is set and building succeeds.
+func (w *Workspace) GenerateCLI(name string, opts CLIOptions) error {
+	if name == "" {
+		return fmt.Errorf("CLI name cannot be empty")
+	}
+
+	// Create CLI directory
+	cliDir := opts.Dir
+	if cliDir == "" {
+		cliDir = w.CLIPath(name)
+	}
+	if err := os.MkdirAll(cliDir, 0755); err != nil {
+		return fmt.Errorf("failed to create CLI directory: %w", err)
+	}
+
+	flavor, err := ParseFlavor(string(opts.Flavor))
+	if err != nil {
+		return err
+	}
+
+	src := w.parseSession()
+	src.extractFlags(opts.Flags)
+	render, reqs, sums := renderCobraMain, cobraRequirements, cobraSums
+	if flavor == StdFlavor {
+		render, reqs, sums = renderStdMain, nil, nil
+	}
+
+	mainPath := filepath.Join(cliDir, "main.go")
+	main := render(name, w.sessionID, src)
+	if err := os.WriteFile(mainPath, formatSource(main.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write main.go: %w", err)
+	}
+
+	// Record dependencies so that `go build` works in the tool directory
+	modDir := w.rootPath
+	if opts.Standalone || !w.contains(cliDir) {
+		modDir = cliDir
+	}
+	if err := w.recordDependencies(modDir, name, reqs, sums, opts.Vendor); err != nil {
+		return err
+	}
+
+	if diags := append(src.flagConflicts(), main.check(cliDir)...); len(diags) > 0 {
+		compileErr := &CompileError{File: mainPath, Diagnostics: diags}
+		// Building checks what the type checker could not
+		if !compileErr.Partial() || !opts.Build {
+			return compileErr
+		}
+	}
+	if opts.Build {
+		return buildTool(cliDir)
+	}
+
+	return nil
+}
+
+// recordDependencies adds the requirements of a generated tool to the go.mod
+// in modDir, which is created for a standalone tool. The workspace lock is
+// held since the workspace go.mod is shared by every gosh instance.
+func (w *Workspace) recordDependencies(modDir, name string, reqs []requirement, sums []string, vendor bool) error {
+	unlock, err := w.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if modDir != w.rootPath {
+		if err := writeModuleFile(modDir, name); err != nil {
+			return err
+		}
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+	if err := addRequirements(modDir, reqs, sums); err != nil {
+		return err
+	}
+	if vendor {
+		return vendorModules(modDir)
+	}
+	return nil
+}
+
+// CLIPath returns the directory of the generated CLI tool with the given name
+func (w *Workspace) CLIPath(name string) string {
+	return filepath.Join(w.rootPath, "cmd", name)
+}
+
+// contains reports whether dir is inside the workspace module
+func (w *Workspace) contains(dir string) bool {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(w.rootPath, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// FlagCandidates lists the session variables that can become CLI flags
+func (w *Workspace) FlagCandidates() []FlagCandidate {
+	var candidates []FlagCandidate
+	seen := make(map[string]bool)
+	for _, block := range w.codeBlocks {
+		items, err := parseBlock(block.code)
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			if flag, ok := flagFromItem(item); ok && !seen[flag.Name] {
+				seen[flag.Name] = true
+				candidates = append(candidates, flag.FlagCandidate)
+			}
+		}
+	}
+	return candidates
+}
+
+// parseSession splits the session into imports, package-level declarations
+// and statements, and finds the functions usable as subcommands. Only the
+// latest declaration of each name is kept.
+func (w *Workspace) parseSession() *cliSource {
+	src := &cliSource{}
+	for i, block := range w.codeBlocks {
+		items, err := parseBlock(block.code)
+		if err != nil {
+			// Keep code that Go cannot parse on its own as is, so nothing
+			// from the session is silently lost
+			src.stmts = append(src.stmts, sourceItem{kind: stmtItem, text: block.code, line: 1, block: i})
+			continue
+		}
+
+		for _, item := range items {
+			item.block = i
+			switch item.kind {
+			case importItem:
+				for _, spec := range item.node.(*ast.GenDecl).Specs {
+					src.imports = append(src.imports, importSpecSource(spec.(*ast.ImportSpec)))
+				}
+			case declItem:
+				src.decls = append(src.decls, item)
+			default:
+				src.stmts = append(src.stmts, item)
+			}
+		}
+	}
+
+	// Functions redefined while iterating in the shell would not compile
+	// twice: keep the last definition, which is the one subcommands call
+	src.decls = latestDeclarations(src.decls)
+
+	// The session code may use the packages imported before it, but Go
+	// rejects unused imports
+	used := usedPackages(append(slices.Clone(src.decls), src.stmts...))
+	for _, path := range w.imports {
+		if spec := strconv.Quote(path); used[importName(spec)] {
+			src.imports = append(src.imports, spec)
+		}
+	}
+	for _, decl := range src.decls {
+		if fn, ok := decl.node.(*ast.FuncDecl); ok {
+			if command, ok := commandFromFunc(fn); ok {
+				src.commands = append(src.commands, command)
+			}
+		}
+	}
+	return src
+}
+
+// extractFlags moves the variables marked with //gosh:flag or listed in
+// selected out of the session code and into flags
+func (src *cliSource) extractFlags(selected []string) {
+	wanted := make(map[string]bool)
+	for _, name := range selected {
+		wanted[name] = true
+	}
+
+	seen := make(map[string]bool)
+	extract := func(items []sourceItem) []sourceItem {
+		var kept []sourceItem
+		for _, item := range items {
+			if flag, ok := flagFromItem(item); ok && !seen[flag.Name] && (flag.Marked || wanted[flag.Name]) {
+				seen[flag.Name] = true
+				src.flags = append(src.flags, flag)
+				continue
+			}
+			kept = append(kept, item)
+		}
+		return kept
+	}
+	src.decls = extract(src.decls)
+	src.stmts = extract(src.stmts)
+
+	// Declare flags in session order regardless of where they were found
+	sort.SliceStable(src.flags, func(i, j int) bool {
+		a, b := src.flags[i].item, src.flags[j].item
+		return a.block < b.block || (a.block == b.block && a.line < b.line)
+	})
+}
+
+// helpFlags lists the flag names that ask the flag and pflag packages for help
+var helpFlags = map[string]bool{"help": true, "h": true}
+
+// flagConflicts reports the flags that would stop the generated tool at
+// startup, because another variable has the same kebab-case name, or hide
+// its help
+func (src *cliSource) flagConflicts() []Diagnostic {
+	var diags []Diagnostic
+	defined := make(map[string]string)
+	for _, flag := range src.flags {
+		name := flagName(flag.Name)
+		diag := Diagnostic{Block: flag.item.block + 1, Line: flag.item.line}
+		if other, ok := defined[name]; ok {
+			diag.Message = fmt.Sprintf("flag %s of variable %s is already defined by variable %s", name, flag.Name, other)
+		} else if helpFlags[name] {
+			diag.Message = fmt.Sprintf("flag %s of variable %s is reserved for help", name, flag.Name)
+		} else {
+			defined[name] = flag.Name
+			continue
+		}
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+// flagFromItem recognizes `x := lit` statements and `var x [T] = lit`
+// declarations of a single variable with a basic type
+func flagFromItem(item sourceItem) (cliFlag, bool) {
+	var name *ast.Ident
+	var typ, value ast.Expr
+
+	switch n := item.node.(type) {
+	case *ast.AssignStmt:
+		if n.Tok != token.DEFINE || len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+			return cliFlag{}, false
+		}
+		ident, ok := n.Lhs[0].(*ast.Ident)
+		if !ok {
+			return cliFlag{}, false
+		}
+		name, value = ident, n.Rhs[0]
+	case *ast.GenDecl:
+		if n.Tok != token.VAR || len(n.Specs) != 1 {
+			return cliFlag{}, false
+		}
+		spec := n.Specs[0].(*ast.ValueSpec)
+		if len(spec.Names) != 1 || len(spec.Values) != 1 {
+			return cliFlag{}, false
+		}
+		name, typ, value = spec.Names[0], spec.Type, spec.Values[0]
+	case *ast.DeclStmt:
+		return flagFromItem(sourceItem{node: n.Decl, text: item.text})
+	default:
+		return cliFlag{}, false
+	}
+
+	if name.Name == "_" {
+		return cliFlag{}, false
+	}
+
+	goType, ok := literalType(value)
+	if !ok {
+		return cliFlag{}, false
+	}
+	if typ != nil {
+		declared, ok := typ.(*ast.Ident)
+		if !ok || !assignableLiteral(goType, declared.Name) {
+			return cliFlag{}, false
+		}
+		goType = declared.Name
+	}
+
+	return cliFlag{
+		FlagCandidate: FlagCandidate{
+			Name:    name.Name,
+			Type:    goType,
+			Default: types.ExprString(value),
+			Marked:  strings.Contains(item.text, flagMarker),
+		},
+		usage: commentText(item.text),
+		item:  item,
+	}, true
+}
+
+// commandFromFunc recognizes exported functions whose parameters all have a
+// type that can be parsed from a command-line argument and which return
+// nothing or an error
+func commandFromFunc(fn *ast.FuncDecl) (cliCommand, bool) {
+	if fn.Recv != nil || !fn.Name.IsExported() || fn.Type.TypeParams != nil {
+		return cliCommand{}, false
+	}
+
+	command := cliCommand{funcName: fn.Name.Name}
+	for _, field := range fn.Type.Params.List {
+		typ, ok := field.Type.(*ast.Ident)
+		if !ok {
+			return cliCommand{}, false
+		}
+		if _, ok := flagTypes[typ.Name]; !ok {
+			return cliCommand{}, false
+		}
+		if len(field.Names) == 0 {
+			command.params = append(command.params, cliParam{name: fmt.Sprintf("arg%d", len(command.params)), typ: typ.Name})
+		}
+		for _, name := range field.Names {
+			command.params = append(command.params, cliParam{name: name.Name, typ: typ.Name})
+		}
+	}
+
+	if results := fn.Type.Results; results != nil && len(results.List) > 0 {
+		if len(results.List) != 1 || len(results.List[0].Names) > 1 {
+			return cliCommand{}, false
+		}
+		if ident, ok := results.List[0].Type.(*ast.Ident); !ok || ident.Name != "error" {
+			return cliCommand{}, false
+		}
+		command.returnsError = true
+	}
+
+	if fn.Doc != nil {
+		command.short = strings.TrimSpace(strings.SplitN(fn.Doc.Text(), "\n", 2)[0])
+	}
+	if command.short == "" {
+		command.short = "Run " + fn.Name.Name + " from the gosh session"
+	}
+	return command, true
+}
+
+// literalType returns the default type of a basic literal expression
+func literalType(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT:
+			return "int", true
+		case token.FLOAT:
+			return "float64", true
+		case token.STRING:
+			return "string", true
+		}
+	case *ast.Ident:
+		if e.Name == "true" || e.Name == "false" {
+			return "bool", true
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := e.X.(*ast.BasicLit); ok && (e.Op == token.SUB || e.Op == token.ADD) {
+			if lit.Kind == token.INT || lit.Kind == token.FLOAT {
+				return literalType(lit)
+			}
+		}
+	case *ast.ParenExpr:
+		return literalType(e.X)
+	}
+	return "", false
+}
+
+// assignableLiteral reports whether a literal of type literal can initialize
+// a flag variable declared with type declared
+func assignableLiteral(literal, declared string) bool {
+	if _, ok := flagTypes[declared]; !ok {
+		return false
+	}
+	switch declared {
+	case "string", "bool":
+		return literal == declared
+	case "float64":
+		return literal == "int" || literal == "float64"
+	default:
+		return literal == "int"
+	}
+}
+
+// commentText extracts the comments of a source item, except gosh markers,
+// for use as flag usage text
+func commentText(text string) string {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(text))
+
+	var s scanner.Scanner
+	s.Init(file, []byte(text), nil, scanner.ScanComments)
+
+	var parts []string
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.COMMENT || strings.HasPrefix(lit, "//gosh:") {
+			continue
+		}
+		comment := strings.TrimPrefix(lit, "//")
+		if strings.HasPrefix(lit, "/*") {
+			comment = strings.TrimSuffix(strings.TrimPrefix(lit, "/*"), "*/")
+		}
+		if c := strings.TrimSpace(comment); c != "" {
+			parts = append(parts, c)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// flagName converts a Go identifier to a kebab-case flag name
+func flagName(ident string) string {
+	runes := []rune(ident)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('-')
+			}
+		}
+		if r == '_' {
+			b.WriteRune('-')
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// importSpecSource renders an import spec as it appears in an import block
+func importSpecSource(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name + " " + spec.Path.Value
+	}
+	return spec.Path.Value
+}
+
+// renderImports renders an import block with the given required imports
+// and the session imports that are not already present, standard library
+// packages first
+func renderImports(b *strings.Builder, required []string, session []string) {
+	seen := make(map[string]bool)
+	var std, external []string
+	for _, imp := range append(append([]string{}, required...), session...) {
+		if seen[imp] {
+			continue
+		}
+		seen[imp] = true
+
+		path := strings.Trim(imp[strings.IndexAny(imp, "\"`"):], "\"`")
+		if first := strings.SplitN(path, "/", 2)[0]; strings.Contains(first, ".") {
+			external = append(external, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+
+	if len(std)+len(external) == 1 {
+		fmt.Fprintf(b, "import %s\n\n", append(std, external...)[0])
+		return
+	}
+
+	b.WriteString("import (\n")
+	for _, imp := range std {
+		fmt.Fprintf(b, "\t%s\n", imp)
+	}
+	if len(std) > 0 && len(external) > 0 {
+		b.WriteString("\n")
+	}
+	for _, imp := range external {
+		fmt.Fprintf(b, "\t%s\n", imp)
+	}
+	b.WriteString(")\n\n")
+}
+
+// renderArgParsing renders the conversion of positional arguments args[i]
+// into variables p<i> of the parameter types
+func renderArgParsing(b *strings.Builder, params []cliParam) {
+	for i, param := range params {
+		arg := fmt.Sprintf("args[%d]", i)
+		var parse string
+		switch param.typ {
+		case "string":
+			fmt.Fprintf(b, "\t\tp%d := %s\n", i, arg)
+			continue
+		case "bool":
+			parse = fmt.Sprintf("strconv.ParseBool(%s)", arg)
+		case "int":
+			parse = fmt.Sprintf("strconv.Atoi(%s)", arg)
+		case "int64":
+			parse = fmt.Sprintf("strconv.ParseInt(%s, 10, 64)", arg)
+		case "uint":
+			parse = fmt.Sprintf("strconv.ParseUint(%s, 10, 0)", arg)
+		case "uint64":
+			parse = fmt.Sprintf("strconv.ParseUint(%s, 10, 64)", arg)
+		case "float64":
+			parse = fmt.Sprintf("strconv.ParseFloat(%s, 64)", arg)
+		}
+		fmt.Fprintf(b, "\t\tp%d, err := %s\n\t\tif err != nil {\n\t\t\treturn fmt.Errorf(\"invalid %s: %%w\", err)\n\t\t}\n",
+			i, parse, param.name)
+	}
+}
+
+// commandUse returns the subcommand name followed by its arguments
+func commandUse(command cliCommand) string {
+	use := flagName(command.funcName)
+	for _, param := range command.params {
+		use += " <" + param.name + ">"
+	}
+	return use
+}
+
+// argList renders the arguments passing parsed parameters to the function
+func argList(params []cliParam) string {
+	args := make([]string, len(params))
+	for i, param := range params {
+		args[i] = fmt.Sprintf("p%d", i)
+		if param.typ == "uint" {
+			// strconv.ParseUint always returns a uint64
+			args[i] = fmt.Sprintf("uint(p%d)", i)
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+// needsStrconv reports whether parsing subcommand arguments uses strconv
+func needsStrconv(commands []cliCommand) bool {
+	for _, command := range commands {
+		for _, param := range command.params {
+			if param.typ != "string" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renderDeclarations renders the package-level variables backing flags and
+// the declarations of the session
+func renderDeclarations(f *genFile, src *cliSource) {
+	if len(src.flags) > 0 {
+		f.WriteString("// Flags generated from session variables\nvar (\n")
+		for _, flag := range src.flags {
+			fmt.Fprintf(f, "\t%s %s\n", flag.Name, flag.Type)
+		}
+		f.WriteString(")\n\n")
+	}
+
+	for _, decl := range src.decls {
+		f.writeItem(decl)
+		f.WriteString("\n\n")
+	}
+}
+
+// formatSource gofmts generated code, falling back to the unformatted code
+// so that a syntax error can still be inspected in the written file
+func formatSource(src string) []byte {
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return []byte(src)
+	}
+	return formatted
+}
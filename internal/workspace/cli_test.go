@@ -0,0 +1,380 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFlagCandidates(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	blocks := []string{
+		"url := \"https://example.com\" //gosh:flag",
+		"count := 3\nverbose := false",
+		"var ratio float64 = 1",
+		"items := []int{1, 2}",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	want := []FlagCandidate{
+		{Name: "url", Type: "string", Default: `"https://example.com"`, Marked: true},
+		{Name: "count", Type: "int", Default: "3"},
+		{Name: "verbose", Type: "bool", Default: "false"},
+		{Name: "ratio", Type: "float64", Default: "1"},
+	}
+
+	got := ws.FlagCandidates()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d candidates, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Candidate %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestGenerateCLIFlags(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	blocks := []string{
+		"// endpoint to query\nurl := \"https://example.com\" //gosh:flag",
+		"maxCount := 3",
+		"fmt.Println(url, maxCount)",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	cliName := "test_flags_cli"
+	if err := ws.GenerateCLI(cliName, CLIOptions{Flags: []string{"maxCount"}}); err != nil {
+		t.Fatalf("Failed to generate CLI: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(ws.CLIPath(cliName), "main.go"))
+	if err != nil {
+		t.Fatalf("Failed to read main.go: %v", err)
+	}
+	main := string(content)
+
+	for _, want := range []string{
+		`rootCmd.PersistentFlags().StringVar(&url, "url", "https://example.com", "endpoint to query")`,
+		`rootCmd.PersistentFlags().IntVar(&maxCount, "max-count", 3, "value of session variable maxCount")`,
+		"fmt.Println(url, maxCount)",
+	} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.go should contain %q, got:\n%s", want, main)
+		}
+	}
+
+	if strings.Contains(main, "maxCount := 3") {
+		t.Error("Flag variables should not be redeclared in the command body")
+	}
+}
+
+func TestGenerateCLIFlagConflicts(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	blocks := []string{
+		"maxCount := 3",
+		"x := 1\nmax_count := 4",
+		"help := false",
+		"fmt.Println(maxCount, max_count, help, x)",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	for _, flavor := range []Flavor{CobraFlavor, StdFlavor} {
+		err := ws.GenerateCLI("conflicts-"+string(flavor), CLIOptions{
+			Flavor: flavor,
+			Flags:  []string{"maxCount", "max_count", "help"},
+		})
+
+		var compileErr *CompileError
+		if !errors.As(err, &compileErr) {
+			t.Fatalf("%s: expected a compile error, got %v", flavor, err)
+		}
+		want := []Diagnostic{
+			{Block: 2, Line: 2, Message: "flag max-count of variable max_count is already defined by variable maxCount"},
+			{Block: 3, Line: 1, Message: "flag help of variable help is reserved for help"},
+		}
+		if !slices.Equal(compileErr.Diagnostics, want) {
+			t.Errorf("%s: expected %v, got %v", flavor, want, compileErr.Diagnostics)
+		}
+	}
+}
+
+func TestGenerateCLIDeclarations(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	block := "import \"strings\"\n\nfunc shout(s string) string {\n\treturn strings.ToUpper(s)\n}\nfmt.Println(shout(\"hi\"))"
+	if err := ws.AddCodeBlock(block); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	cliName := "test_decls_cli"
+	if err := ws.GenerateCobraCLI(cliName); err != nil {
+		t.Fatalf("Failed to generate CLI: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(ws.CLIPath(cliName), "main.go"))
+	if err != nil {
+		t.Fatalf("Failed to read main.go: %v", err)
+	}
+	main := string(content)
+
+	funcIdx := strings.Index(main, "func shout(")
+	rootIdx := strings.Index(main, "var rootCmd")
+	if funcIdx < 0 || rootIdx < 0 || funcIdx > rootIdx {
+		t.Errorf("Session functions should be declared at package level, got:\n%s", main)
+	}
+	if !strings.Contains(main, `"strings"`) {
+		t.Error("main.go should import packages imported in the session")
+	}
+}
+
+func TestGenerateCLISubcommands(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	blocks := []string{
+		"// Greet prints a greeting\nfunc Greet(name string, times int) {\n\tfor i := 0; i < times; i++ {\n\t\tfmt.Println(\"hello\", name)\n\t}\n}",
+		"func Check(strict bool) error {\n\treturn nil\n}",
+		"func helper() {}",
+		"func Sum(values []int) int {\n\treturn 0\n}",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	cliName := "test_subcommands_cli"
+	if err := ws.GenerateCobraCLI(cliName); err != nil {
+		t.Fatalf("Failed to generate CLI: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(ws.CLIPath(cliName), "main.go"))
+	if err != nil {
+		t.Fatalf("Failed to read main.go: %v", err)
+	}
+	main := string(content)
+
+	for _, want := range []string{
+		`Use:   "greet <name> <times>"`,
+		`Short: "Greet prints a greeting"`,
+		"p1, err := strconv.Atoi(args[1])",
+		"Greet(p0, p1)",
+		"return Check(p0)",
+		"rootCmd.AddCommand(cmdGreet)",
+		"rootCmd.AddCommand(cmdCheck)",
+	} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.go should contain %q, got:\n%s", want, main)
+		}
+	}
+
+	for _, unwanted := range []string{"cmdhelper", "cmdSum", "Run: func"} {
+		if strings.Contains(main, unwanted) {
+			t.Errorf("main.go should not contain %q", unwanted)
+		}
+	}
+}
+
+func TestGenerateCLIRedefinedSubcommands(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	blocks := []string{
+		"func Greet(name string) {\n\tfmt.Println(\"hi\", name)\n}",
+		"func Greet(name string) {\n\tfmt.Println(\"hello\", name)\n}",
+		"func Greet(name string, times int) {\n\tfmt.Println(name, times)\n}",
+		"func Wave(name string) {\n\tfmt.Println(name)\n}",
+		"func Wave(names []string) {\n\tfmt.Println(names)\n}",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	tests := map[Flavor]struct {
+		once, unwanted string
+	}{
+		CobraFlavor: {once: "var cmdGreet =", unwanted: "cmdWave"},
+		StdFlavor:   {once: "func runGreet(", unwanted: "runWave"},
+	}
+	for flavor, tt := range tests {
+		cliName := "test_redefined_" + string(flavor)
+		if err := ws.GenerateCLI(cliName, CLIOptions{Flavor: flavor}); err != nil {
+			t.Fatalf("%s: failed to generate CLI: %v", flavor, err)
+		}
+		content, err := os.ReadFile(filepath.Join(ws.CLIPath(cliName), "main.go"))
+		if err != nil {
+			t.Fatalf("Failed to read main.go: %v", err)
+		}
+		main := string(content)
+
+		if count := strings.Count(main, tt.once); count != 1 {
+			t.Errorf("%s: expected %q once, got %d times in:\n%s", flavor, tt.once, count, main)
+		}
+		if !strings.Contains(main, "Greet(p0, p1)") {
+			t.Errorf("%s: expected the latest Greet to be called, got:\n%s", flavor, main)
+		}
+		if strings.Contains(main, tt.unwanted) {
+			t.Errorf("%s: Wave no longer takes command-line arguments, got:\n%s", flavor, main)
+		}
+	}
+}
+
+func TestGenerateCLIPreImports(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	ws.SetImports([]string{"fmt", "strings", "os"})
+
+	blocks := []string{
+		"func Shout(s string) string {\n\treturn strings.ToUpper(s)\n}",
+		"fmt.Println(Shout(\"hi\"))",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	for _, flavor := range []Flavor{CobraFlavor, StdFlavor} {
+		cliName := "test_preimports_" + string(flavor)
+		if err := ws.GenerateCLI(cliName, CLIOptions{Flavor: flavor}); err != nil {
+			t.Fatalf("%s: failed to generate CLI: %v", flavor, err)
+		}
+	}
+
+	dir, err := ws.GeneratePackage("shout", "")
+	if err != nil {
+		t.Fatalf("Failed to export package: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "shout.go"))
+	if err != nil {
+		t.Fatalf("Failed to read package: %v", err)
+	}
+	if pkg := string(content); !strings.Contains(pkg, `import "strings"`) {
+		t.Errorf("Expected the package to import strings, got:\n%s", pkg)
+	}
+}
+
+func TestGenerateStdCLI(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	blocks := []string{
+		"count := 2 //gosh:flag",
+		"// Greet prints a greeting\nfunc Greet(name string) {\n\tfmt.Println(\"hello\", name)\n}",
+		"for i := 0; i < count; i++ {\n\tGreet(\"gosh\")\n}",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	cliName := "test_std_cli"
+	if err := ws.GenerateStdCLI(cliName); err != nil {
+		t.Fatalf("Failed to generate CLI: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(ws.CLIPath(cliName), "main.go"))
+	if err != nil {
+		t.Fatalf("Failed to read main.go: %v", err)
+	}
+	main := string(content)
+
+	if strings.Contains(main, "cobra") {
+		t.Error("Standard library CLI should not depend on cobra")
+	}
+	for _, want := range []string{
+		`flag.IntVar(&count, "count", 2, "value of session variable count")`,
+		`case "greet":`,
+		"return runGreet(args[1:])",
+		"Greet(p0)",
+	} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.go should contain %q, got:\n%s", want, main)
+		}
+	}
+}
+
+func TestParseFlavor(t *testing.T) {
+	for name, want := range map[string]Flavor{"": CobraFlavor, "cobra": CobraFlavor, "STD": StdFlavor} {
+		got, err := ParseFlavor(name)
+		if err != nil || got != want {
+			t.Errorf("ParseFlavor(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseFlavor("urfave"); err == nil {
+		t.Error("Expected an error for an unknown flavor")
+	}
+}
+
+func TestFlagName(t *testing.T) {
+	tests := map[string]string{
+		"url":         "url",
+		"maxCount":    "max-count",
+		"maxURLCount": "max-url-count",
+		"retry_limit": "retry-limit",
+	}
+	for ident, want := range tests {
+		if got := flagName(ident); got != want {
+			t.Errorf("flagName(%q) = %q, want %q", ident, got, want)
+		}
+	}
+}
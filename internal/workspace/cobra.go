@@ -0,0 +1,76 @@
+package workspace
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// renderCobraMain renders the main.go of a Cobra-based CLI tool
+func renderCobraMain(name, sessionID string, src *cliSource) *genFile {
+	f := &genFile{}
+	b := &f.Builder
+	b.WriteString("package main\n\n")
+	required := []string{`"fmt"`, `"os"`}
+	if needsStrconv(src.commands) {
+		required = append(required, `"strconv"`)
+	}
+	renderImports(b, append(required, `"github.com/spf13/cobra"`), src.imports)
+
+	renderDeclarations(f, src)
+
+	fmt.Fprintf(b, "var rootCmd = &cobra.Command{\n\tUse:   %s,\n\tShort: %s,\n",
+		strconv.Quote(name), strconv.Quote("Generated CLI from gosh session "+sessionID))
+	// Without session statements the root command only lists subcommands
+	if len(src.stmts) > 0 || len(src.commands) == 0 {
+		b.WriteString("\tRun: func(cmd *cobra.Command, args []string) {\n\t\t// Session code\n")
+		for _, stmt := range src.stmts {
+			f.writeItem(stmt)
+			b.WriteString("\n")
+		}
+		b.WriteString("\t},\n")
+	}
+	b.WriteString("}\n\n")
+
+	for _, command := range src.commands {
+		renderCobraCommand(b, command)
+	}
+
+	if len(src.flags) > 0 || len(src.commands) > 0 {
+		b.WriteString("func init() {\n")
+		for _, flag := range src.flags {
+			fmt.Fprintf(b, "\trootCmd.PersistentFlags().%sVar(&%s, %q, %s, %q)\n",
+				flagTypes[flag.Type], flag.Name, flagName(flag.Name), flag.Default, flag.usageText())
+		}
+		for _, command := range src.commands {
+			fmt.Fprintf(b, "\trootCmd.AddCommand(cmd%s)\n", command.funcName)
+		}
+		b.WriteString("}\n\n")
+	}
+
+	b.WriteString(`func main() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`)
+
+	return f
+}
+
+// renderCobraCommand renders the subcommand calling a session function with
+// its positional arguments
+func renderCobraCommand(b *strings.Builder, command cliCommand) {
+	fmt.Fprintf(b, "var cmd%s = &cobra.Command{\n\tUse:   %q,\n\tShort: %q,\n\tArgs:  cobra.ExactArgs(%d),\n",
+		command.funcName, commandUse(command), command.short, len(command.params))
+	b.WriteString("\tRunE: func(cmd *cobra.Command, args []string) error {\n")
+	renderArgParsing(b, command.params)
+	call := fmt.Sprintf("%s(%s)", command.funcName, argList(command.params))
+	if command.returnsError {
+		fmt.Fprintf(b, "\t\treturn %s\n", call)
+	} else {
+		fmt.Fprintf(b, "\t\t%s\n\t\treturn nil\n", call)
+	}
+	b.WriteString("\t},\n}\n\n")
+}
@@ -0,0 +1,154 @@
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// goVersion is the go directive written to generated go.mod files
+const goVersion = "1.25"
+
+// requirement is a pinned module dependency of generated code
+type requirement struct {
+	path     string
+	version  string
+	indirect bool
+}
+
+// cobraRequirements are the modules needed to build a Cobra-based CLI tool
+var cobraRequirements = []requirement{
+	{path: "github.com/spf13/cobra", version: "v1.10.1"},
+	{path: "github.com/inconshreveable/mousetrap", version: "v1.1.0", indirect: true},
+	{path: "github.com/spf13/pflag", version: "v1.0.9", indirect: true},
+}
+
+// cobraSums are the go.sum entries matching cobraRequirements, so that the
+// generated tool builds without running go mod tidy first
+var cobraSums = []string{
+	"github.com/cpuguy83/go-md2man/v2 v2.0.6/go.mod h1:oOW0eioCTA6cOiMLiUPZOpcVxMig6NIQQ7OS05n1F4g=",
+	"github.com/inconshreveable/mousetrap v1.1.0 h1:wN+x4NVGpMsO7ErUn/mUI3vEoE6Jt13X2s0bqwp9tc8=",
+	"github.com/inconshreveable/mousetrap v1.1.0/go.mod h1:vpF70FUmC8bwa3OWnCshd2FqLfsEA9PFc4w1p2J65bw=",
+	"github.com/russross/blackfriday/v2 v2.1.0/go.mod h1:+Rmxgy9KzJVeS9/2gXHxylqXiyQDYRxCVz55jmeOWTM=",
+	"github.com/spf13/cobra v1.10.1 h1:lJeBwCfmrnXthfAupyUTzJ/J4Nc1RsHC/mSRU2dll/s=",
+	"github.com/spf13/cobra v1.10.1/go.mod h1:7SmJGaTHFVBY0jW4NXGluQoLvhqFQM+6XSKD+P4XaB0=",
+	"github.com/spf13/pflag v1.0.9 h1:9exaQaMOCwffKiiiYk6/BndUBv+iRViNW+4lEMi0PvY=",
+	"github.com/spf13/pflag v1.0.9/go.mod h1:McXfInJRrz4CZXVZOBLb0bTZqETkiAhM9Iw0y3An2Bg=",
+	"gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405/go.mod h1:Co6ibVJAznAaIkqp8huTwlJQCZ016jof/cbN4VW5Yz0=",
+	"gopkg.in/yaml.v3 v3.0.1/go.mod h1:K4uyk7z7BCEPqu6E+C64Yfv1cQ7kz7rIZviUmN+EgEM=",
+}
+
+// writeModuleFile creates a go.mod declaring the given module path
+func writeModuleFile(dir, modulePath string) error {
+	content := fmt.Sprintf("module %s\n\ngo %s\n", modulePath, goVersion)
+	if err := writeFileAtomic(filepath.Join(dir, "go.mod"), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to create go.mod: %w", err)
+	}
+	return nil
+}
+
+// addRequirements adds the missing require directives to the go.mod in dir
+// and merges the matching checksums into its go.sum
+func addRequirements(dir string, reqs []requirement, sums []string) error {
+	goModPath := filepath.Join(dir, "go.mod")
+	content, err := os.ReadFile(goModPath)
+	if err != nil {
+		return fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	var missing []requirement
+	for _, req := range reqs {
+		if !requiresModule(content, req.path) {
+			missing = append(missing, req)
+		}
+	}
+
+	if len(missing) > 0 {
+		var block strings.Builder
+		block.WriteString("\nrequire (\n")
+		for _, req := range missing {
+			fmt.Fprintf(&block, "\t%s %s", req.path, req.version)
+			if req.indirect {
+				block.WriteString(" // indirect")
+			}
+			block.WriteString("\n")
+		}
+		block.WriteString(")\n")
+
+		if !bytes.HasSuffix(content, []byte("\n")) {
+			content = append(content, '\n')
+		}
+		content = append(content, block.String()...)
+		if err := writeFileAtomic(goModPath, content, 0644); err != nil {
+			return fmt.Errorf("failed to update go.mod: %w", err)
+		}
+	}
+
+	return mergeSums(filepath.Join(dir, "go.sum"), sums)
+}
+
+// requiresModule reports whether go.mod content already requires modulePath
+func requiresModule(content []byte, modulePath string) bool {
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == "require" {
+			fields = fields[1:]
+		}
+		if len(fields) > 0 && fields[0] == modulePath {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSums adds the given lines to a go.sum file, keeping it sorted and
+// free of duplicates
+func mergeSums(goSumPath string, sums []string) error {
+	lines := make(map[string]bool)
+	if existing, err := os.ReadFile(goSumPath); err == nil {
+		for _, line := range strings.Split(string(existing), "\n") {
+			if line != "" {
+				lines[line] = true
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read go.sum: %w", err)
+	}
+
+	for _, line := range sums {
+		lines[line] = true
+	}
+
+	sorted := make([]string, 0, len(lines))
+	for line := range lines {
+		sorted = append(sorted, line)
+	}
+	sort.Strings(sorted)
+
+	content := strings.Join(sorted, "\n") + "\n"
+	if err := writeFileAtomic(goSumPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write go.sum: %w", err)
+	}
+	return nil
+}
+
+// vendorModules copies the dependencies of the module in dir into its vendor
+// directory using only the local module cache, so it works offline
+func vendorModules(dir string) error {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return fmt.Errorf("go toolchain not found, cannot vendor dependencies: %w", err)
+	}
+
+	cmd := exec.Command(goBin, "mod", "vendor")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod", "GOWORK=off")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to vendor dependencies from module cache: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
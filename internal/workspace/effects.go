@@ -0,0 +1,338 @@
+package workspace
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// Markers setting whether reload re-runs the side effects of a block
+const (
+	// RerunMarker re-runs the statements of the block without asking
+	RerunMarker = "//gosh:rerun"
+	// OnceMarker never re-runs the statements with side effects of the block
+	OnceMarker = "//gosh:once"
+)
+
+// ReloadStep is a part of a session block evaluated on its own by reload
+type ReloadStep struct {
+	Code string
+	// SideEffects is set when the step may act outside the interpreter, such
+	// as printing, writing files or sending requests
+	SideEffects bool
+	// Defines lists the names the step declares or assigns, and Uses the
+	// names it refers to, so that steps using the result of a skipped one
+	// can be skipped too
+	Defines []string
+	Uses    []string
+}
+
+// ReloadBlock is a session block split into the steps evaluated by reload
+type ReloadBlock struct {
+	Steps []ReloadStep
+	Rerun bool // marked with RerunMarker
+	Once  bool // marked with OnceMarker
+}
+
+// HasSideEffects reports whether any step of the block has side effects
+func (b ReloadBlock) HasSideEffects() bool {
+	for _, step := range b.Steps {
+		if step.SideEffects {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanReload splits blocks into imports, declarations and statements, and
+// flags those that may have side effects. Imports, function, type and
+// constant declarations never do; variables and statements do when they
+// call anything but builtins, conversions, a few computing-only standard
+// library functions and session functions that only call those. A block
+// that does not parse is a single step with side effects.
+func PlanReload(blocks []string) []ReloadBlock {
+	parsed := make([][]sourceItem, len(blocks))
+	checker := &effectChecker{
+		funcs:    make(map[string]*ast.FuncDecl),
+		types:    make(map[string]bool),
+		checking: make(map[string]bool),
+	}
+	for i, block := range blocks {
+		items, err := parseBlock(block)
+		if err != nil {
+			continue
+		}
+		parsed[i] = items
+		for _, item := range items {
+			checker.declare(item)
+		}
+	}
+
+	plan := make([]ReloadBlock, len(blocks))
+	for i, block := range blocks {
+		plan[i].Rerun = hasMarker(block, RerunMarker)
+		plan[i].Once = hasMarker(block, OnceMarker)
+		if parsed[i] == nil {
+			plan[i].Steps = []ReloadStep{{Code: block, SideEffects: true}}
+			continue
+		}
+		for _, item := range parsed[i] {
+			defines, uses := stepNames(item.node)
+			plan[i].Steps = append(plan[i].Steps, ReloadStep{
+				Code:        item.text,
+				SideEffects: checker.itemEffects(item),
+				Defines:     defines,
+				Uses:        uses,
+			})
+		}
+	}
+	return plan
+}
+
+// hasMarker reports whether a line of code is the given marker
+func hasMarker(code, marker string) bool {
+	for _, line := range strings.Split(code, "\n") {
+		if strings.TrimSpace(line) == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// stepNames returns the names a step declares or assigns, and the names it
+// refers to. Scopes are ignored: a local variable shadowing a session one
+// counts as a use of it.
+func stepNames(node ast.Node) (defines, uses []string) {
+	define := func(expr ast.Expr) {
+		// Assigning an element or field changes the variable holding it
+		for {
+			switch e := expr.(type) {
+			case *ast.IndexExpr:
+				expr = e.X
+				continue
+			case *ast.SelectorExpr:
+				expr = e.X
+				continue
+			case *ast.StarExpr:
+				expr = e.X
+				continue
+			case *ast.ParenExpr:
+				expr = e.X
+				continue
+			case *ast.Ident:
+				if e.Name != "_" {
+					defines = append(defines, e.Name)
+				}
+			}
+			return
+		}
+	}
+
+	var inspect func(ast.Node) bool
+	inspect = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Recv == nil {
+				define(n.Name)
+			} else {
+				ast.Inspect(n.Recv, inspect)
+			}
+			ast.Inspect(n.Type, inspect)
+			if n.Body != nil {
+				ast.Inspect(n.Body, inspect)
+			}
+			return false
+		case *ast.TypeSpec:
+			define(n.Name)
+			if n.TypeParams != nil {
+				ast.Inspect(n.TypeParams, inspect)
+			}
+			ast.Inspect(n.Type, inspect)
+			return false
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				define(name)
+			}
+			if n.Type != nil {
+				ast.Inspect(n.Type, inspect)
+			}
+			for _, value := range n.Values {
+				ast.Inspect(value, inspect)
+			}
+			return false
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				define(lhs)
+				// Declared names are not uses, unlike the indexes and
+				// receivers of assigned elements and fields
+				if _, ok := lhs.(*ast.Ident); !ok {
+					ast.Inspect(lhs, inspect)
+				}
+			}
+			for _, rhs := range n.Rhs {
+				ast.Inspect(rhs, inspect)
+			}
+			return false
+		case *ast.IncDecStmt:
+			define(n.X)
+		case *ast.SelectorExpr:
+			// Only the operand refers to a name of the session
+			ast.Inspect(n.X, inspect)
+			return false
+		case *ast.Ident:
+			uses = append(uses, n.Name)
+		}
+		return true
+	}
+	ast.Inspect(node, inspect)
+	return defines, uses
+}
+
+// pureBuiltins lists the builtin functions and types that only compute a
+// result when called
+var pureBuiltins = map[string]bool{
+	"append": true, "cap": true, "clear": true, "complex": true, "copy": true,
+	"delete": true, "imag": true, "len": true, "make": true, "max": true,
+	"min": true, "new": true, "panic": true, "real": true, "recover": true,
+
+	"any": true, "bool": true, "byte": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true,
+	"int16": true, "int32": true, "int64": true, "rune": true, "string": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"uintptr": true,
+}
+
+// pureFuncs lists the standard library functions that only compute a
+// result, by package name; "*" stands for every function of the package
+var pureFuncs = map[string]map[string]bool{
+	"bits":     {"*": true},
+	"bytes":    {"*": true},
+	"cmp":      {"*": true},
+	"errors":   {"*": true},
+	"maps":     {"*": true},
+	"math":     {"*": true},
+	"path":     {"*": true},
+	"regexp":   {"*": true},
+	"slices":   {"*": true},
+	"sort":     {"*": true},
+	"strconv":  {"*": true},
+	"strings":  {"*": true},
+	"unicode":  {"*": true},
+	"utf8":     {"*": true},
+	"filepath": {"Base": true, "Clean": true, "Dir": true, "Ext": true, "IsAbs": true, "Join": true, "Match": true, "Rel": true, "Split": true},
+	"fmt":      {"Errorf": true, "Sprint": true, "Sprintf": true, "Sprintln": true},
+	"json":     {"Marshal": true, "MarshalIndent": true, "Unmarshal": true, "Valid": true},
+	"time":     {"Date": true, "Duration": true, "ParseDuration": true, "Unix": true, "UnixMilli": true},
+}
+
+// effectChecker finds the side effects of code, following calls to the
+// functions declared in the session
+type effectChecker struct {
+	funcs    map[string]*ast.FuncDecl
+	types    map[string]bool
+	checking map[string]bool // functions being checked, assumed pure meanwhile
+}
+
+// declare records the functions and types declared by an item. A name
+// declared again refers to its last declaration.
+func (c *effectChecker) declare(item sourceItem) {
+	switch n := item.node.(type) {
+	case *ast.FuncDecl:
+		if n.Recv == nil {
+			c.funcs[n.Name.Name] = n
+		}
+	case *ast.GenDecl:
+		if n.Tok != token.TYPE {
+			return
+		}
+		for _, spec := range n.Specs {
+			c.types[spec.(*ast.TypeSpec).Name.Name] = true
+		}
+	}
+}
+
+// itemEffects reports whether evaluating an item may have side effects
+func (c *effectChecker) itemEffects(item sourceItem) bool {
+	switch item.kind {
+	case importItem:
+		return false
+	case declItem:
+		// Declaring functions, types and constants runs nothing, unlike the
+		// initialization of variables
+		if gen, ok := item.node.(*ast.GenDecl); ok && gen.Tok == token.VAR {
+			return c.hasEffects(gen)
+		}
+		return false
+	}
+	return c.hasEffects(item.node)
+}
+
+// hasEffects reports whether running node may have side effects
+func (c *effectChecker) hasEffects(node ast.Node) bool {
+	effects := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		// Inspect still visits the siblings of the node with effects
+		if effects {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Defining a function does not run it
+			return false
+		case *ast.GoStmt, *ast.DeferStmt, *ast.SendStmt, *ast.SelectStmt:
+			effects = true
+		case *ast.UnaryExpr:
+			effects = n.Op == token.ARROW
+		case *ast.CallExpr:
+			effects = !c.pureCall(n)
+		}
+		return !effects
+	})
+	return effects
+}
+
+// pureCall reports whether a call only computes a result, not counting the
+// evaluation of its arguments
+func (c *effectChecker) pureCall(call *ast.CallExpr) bool {
+	fun := ast.Unparen(call.Fun)
+	// Generic functions may be instantiated explicitly
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+
+	switch f := fun.(type) {
+	case *ast.FuncLit:
+		return !c.hasEffects(f.Body)
+	case *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType, *ast.InterfaceType, *ast.StructType, *ast.StarExpr:
+		// Conversions
+		return true
+	case *ast.Ident:
+		if pureBuiltins[f.Name] || c.types[f.Name] {
+			return true
+		}
+		return c.pureFunc(f.Name)
+	case *ast.SelectorExpr:
+		if pkg, ok := f.X.(*ast.Ident); ok {
+			funcs := pureFuncs[pkg.Name]
+			return funcs["*"] || funcs[f.Sel.Name]
+		}
+	}
+	return false
+}
+
+// pureFunc reports whether a session function only computes a result
+func (c *effectChecker) pureFunc(name string) bool {
+	decl, ok := c.funcs[name]
+	if !ok || decl.Body == nil {
+		return false
+	}
+	if c.checking[name] {
+		return true
+	}
+	c.checking[name] = true
+	defer delete(c.checking, name)
+	return !c.hasEffects(decl.Body)
+}
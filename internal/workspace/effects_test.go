@@ -0,0 +1,94 @@
+package workspace
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlanReload(t *testing.T) {
+	blocks := []string{
+		"import \"os\"\n\nfunc double(n int) int { return n * 2 }\n\nfunc save(s string) { os.WriteFile(s, nil, 0644) }\n\ntype ID string",
+		"n := double(len(\"ab\"))\nid := ID(strings.ToUpper(\"x\"))\nfmt.Println(n, id)",
+		"var total = func() int { save(\"x\"); return 1 }\nfor i := range 3 {\n\tn += i\n}\nsave(\"total\")",
+		"//gosh:rerun\nmsg := fmt.Sprintf(\"%d\", n)\nhttp.Get(msg)",
+		"//gosh:once\nos.Remove(\"x\")",
+		"this is not go",
+		// Effects followed by pure siblings
+		"a, b := os.Remove(\"x\"), len(\"s\")\nxs := []int{os.Getpid(), -1}\nv, w := <-ch, -1",
+	}
+	plan := PlanReload(blocks)
+	if len(plan) != len(blocks) {
+		t.Fatalf("Expected %d blocks, got %d", len(blocks), len(plan))
+	}
+
+	tests := []struct {
+		block   int
+		effects []bool
+	}{
+		{0, []bool{false, false, false, false}},
+		{1, []bool{false, false, true}},
+		{2, []bool{false, false, true}},
+		{3, []bool{false, true}},
+		{4, []bool{true}},
+		{5, []bool{true}},
+		{6, []bool{true, true, true}},
+	}
+	for _, tt := range tests {
+		steps := plan[tt.block].Steps
+		if len(steps) != len(tt.effects) {
+			t.Errorf("Block %d: expected %d steps, got %+v", tt.block+1, len(tt.effects), steps)
+			continue
+		}
+		for i, want := range tt.effects {
+			if steps[i].SideEffects != want {
+				t.Errorf("Block %d: expected side effects %v for %q", tt.block+1, want, steps[i].Code)
+			}
+		}
+	}
+
+	if !plan[3].Rerun || plan[3].Once || !plan[4].Once || plan[1].Rerun {
+		t.Error("Expected the markers of blocks 4 and 5 to be detected")
+	}
+	if plan[0].HasSideEffects() || !plan[1].HasSideEffects() {
+		t.Error("Unexpected HasSideEffects result")
+	}
+}
+
+func TestPlanReloadRecursion(t *testing.T) {
+	blocks := []string{
+		"func even(n int) bool { if n == 0 { return true }; return odd(n - 1) }\n\nfunc odd(n int) bool { if n == 0 { return false }; return even(n - 1) }",
+		"ok := even(4)",
+		"func log(s string) { fmt.Println(s) }\n\nfunc run() { log(\"run\") }",
+		"run()",
+	}
+	plan := PlanReload(blocks)
+	if plan[1].HasSideEffects() {
+		t.Error("Mutually recursive pure functions should not have side effects")
+	}
+	if !plan[3].HasSideEffects() {
+		t.Error("Calling a function that prints should have side effects")
+	}
+}
+
+func TestPlanReloadNames(t *testing.T) {
+	plan := PlanReload([]string{
+		"data, err := os.ReadFile(name)\nm[key] = len(data)\ncount++",
+		"func size(p Point) int { return n * p.X }",
+	})
+
+	tests := []struct {
+		step    ReloadStep
+		defines []string
+		uses    []string
+	}{
+		{plan[0].Steps[0], []string{"data", "err"}, []string{"os", "name"}},
+		{plan[0].Steps[1], []string{"m"}, []string{"m", "key", "len", "data"}},
+		{plan[0].Steps[2], []string{"count"}, []string{"count"}},
+		{plan[1].Steps[0], []string{"size"}, []string{"p", "Point", "int", "n", "p"}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(tt.step.Defines, tt.defines) || !slices.Equal(tt.step.Uses, tt.uses) {
+			t.Errorf("%q: expected defines %q and uses %q, got %q and %q", tt.step.Code, tt.defines, tt.uses, tt.step.Defines, tt.step.Uses)
+		}
+	}
+}
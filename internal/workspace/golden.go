@@ -0,0 +1,157 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// yaegiRequirements are the modules needed by generated golden tests, which
+// replay the session through the same interpreter as gosh
+var yaegiRequirements = []requirement{
+	{path: "github.com/traefik/yaegi", version: "v0.16.1"},
+}
+
+// yaegiSums are the go.sum entries matching yaegiRequirements
+var yaegiSums = []string{
+	"github.com/traefik/yaegi v0.16.1 h1:f1De3DVJqIDKmnasUF6MwmWv1dSEEat0wcpXhD2On3E=",
+	"github.com/traefik/yaegi v0.16.1/go.mod h1:4eVhbPb3LnD2VigQjhYbEJ69vDRFdT2HQNrXx8eEwUY=",
+}
+
+// GenerateGoldenTest writes a test that replays the session blocks and
+// compares the standard output of each one with golden files recorded from
+// the session, under testdata/<name>/. The test is written to dir, or to
+// PackagePath(name) when dir is empty, and the directory actually used is
+// returned. Run the test with -update to rewrite the golden files.
+func (w *Workspace) GenerateGoldenTest(name, dir string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("test name cannot be empty")
+	}
+	pkgName := packageName(name)
+	if pkgName == "" {
+		return "", fmt.Errorf("invalid test name %q", name)
+	}
+	if len(w.codeBlocks) == 0 {
+		return "", fmt.Errorf("session has no code blocks to test")
+	}
+
+	inWorkspace := dir == ""
+	if inWorkspace {
+		dir = w.PackagePath(name)
+	}
+
+	goldenDir := filepath.Join(dir, "testdata", pkgName)
+	if err := os.MkdirAll(goldenDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create testdata directory: %w", err)
+	}
+
+	for i, block := range w.codeBlocks {
+		goldenPath := filepath.Join(goldenDir, goldenFileName(i))
+		if err := os.WriteFile(goldenPath, []byte(block.output), 0644); err != nil {
+			return "", fmt.Errorf("failed to write golden file: %w", err)
+		}
+	}
+
+	testPath := filepath.Join(dir, pkgName+"_session_test.go")
+	if err := os.WriteFile(testPath, w.renderGoldenTest(pkgName), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", filepath.Base(testPath), err)
+	}
+
+	// The workspace module needs the interpreter to run the test
+	if inWorkspace {
+		unlock, err := w.lock()
+		if err != nil {
+			return "", err
+		}
+		defer unlock()
+
+		if err := addRequirements(w.rootPath, yaegiRequirements, yaegiSums); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+// goldenFileName returns the name of the golden file of the i-th block
+func goldenFileName(i int) string {
+	return fmt.Sprintf("block_%03d.golden", i+1)
+}
+
+// renderGoldenTest renders the test replaying the session blocks
+func (w *Workspace) renderGoldenTest(pkgName string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s_test\n\n", pkgName)
+	renderImports(&b, []string{
+		`"bytes"`, `"flag"`, `"os"`, `"path/filepath"`, `"testing"`,
+		`"github.com/traefik/yaegi/interp"`, `"github.com/traefik/yaegi/stdlib"`,
+	}, nil)
+
+	b.WriteString("var update = flag.Bool(\"update\", false, \"update golden files\")\n\n")
+
+	// Sessions use fmt without importing it, along with the packages
+	// imported before them
+	preImports := []string{"fmt"}
+	for _, path := range w.imports {
+		if !slices.Contains(preImports, path) {
+			preImports = append(preImports, path)
+		}
+	}
+	quoted := make([]string, len(preImports))
+	for i, path := range preImports {
+		quoted[i] = strconv.Quote(path)
+	}
+	b.WriteString("// preImports are the packages imported before the session blocks\n")
+	fmt.Fprintf(&b, "var preImports = []string{%s}\n\n", strings.Join(quoted, ", "))
+
+	fmt.Fprintf(&b, "// sessionBlocks are the code blocks of gosh session %s, in evaluation order\n", w.sessionID)
+	b.WriteString("var sessionBlocks = []struct {\n\tcode   string\n\tgolden string\n}{\n")
+	for i, block := range w.codeBlocks {
+		fmt.Fprintf(&b, "\t{code: %s, golden: %q},\n", strconv.Quote(block.code), goldenFileName(i))
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(&b, `// TestSession replays the session and checks that every block prints the
+// same output as when it was evaluated in gosh
+func TestSession(t *testing.T) {
+	var out bytes.Buffer
+	i := interp.New(interp.Options{Stdout: &out})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		t.Fatalf("failed to load standard library: %%v", err)
+	}
+	for _, path := range preImports {
+		if _, err := i.Eval(%s + path + %s); err != nil {
+			t.Fatalf("failed to import %%s: %%v", path, err)
+		}
+	}
+
+	for _, block := range sessionBlocks {
+		out.Reset()
+		if _, err := i.Eval(block.code); err != nil {
+			t.Fatalf("%%s: evaluation failed: %%v", block.golden, err)
+		}
+
+		golden := filepath.Join("testdata", %q, block.golden)
+		if *update {
+			if err := os.WriteFile(golden, out.Bytes(), 0644); err != nil {
+				t.Fatalf("failed to update golden file: %%v", err)
+			}
+			continue
+		}
+
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("failed to read golden file: %%v", err)
+		}
+		if got := out.String(); got != string(want) {
+			t.Errorf("%%s: output mismatch\ngot:\n%%s\nwant:\n%%s", block.golden, got, want)
+		}
+	}
+}
+`, "`import \"`", "`\"`", pkgName)
+
+	return formatSource(b.String())
+}
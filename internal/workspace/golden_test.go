@@ -0,0 +1,109 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGoldenTest(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	if err := ws.AddCodeBlockWithOutput(`x := 21`, ""); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if err := ws.AddCodeBlockWithOutput(`fmt.Println(x * 2)`, "42\n"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	dir, err := ws.GenerateGoldenTest("golden-check", t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to generate golden test: %v", err)
+	}
+
+	for i, want := range []string{"", "42\n"} {
+		golden, err := os.ReadFile(filepath.Join(dir, "testdata", "goldencheck", goldenFileName(i)))
+		if err != nil {
+			t.Fatalf("Golden file %d not written: %v", i+1, err)
+		}
+		if string(golden) != want {
+			t.Errorf("Golden file %d: expected %q, got %q", i+1, want, golden)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "goldencheck_session_test.go"))
+	if err != nil {
+		t.Fatalf("Test file not written: %v", err)
+	}
+	test := string(content)
+
+	for _, want := range []string{
+		"package goldencheck_test",
+		`{code: "fmt.Println(x * 2)", golden: "block_002.golden"}`,
+		`filepath.Join("testdata", "goldencheck", block.golden)`,
+		"func TestSession(t *testing.T) {",
+	} {
+		if !strings.Contains(test, want) {
+			t.Errorf("Test file should contain %q, got:\n%s", want, test)
+		}
+	}
+}
+
+func TestGenerateGoldenTestPreImports(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	ws.SetImports([]string{"fmt", "strings"})
+	if err := ws.AddCodeBlockWithOutput(`fmt.Println(strings.ToUpper("hi"))`, "HI\n"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	dir, err := ws.GenerateGoldenTest("pre-imports", t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to generate golden test: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "preimports_session_test.go"))
+	if err != nil {
+		t.Fatalf("Test file not written: %v", err)
+	}
+	if want := `var preImports = []string{"fmt", "strings"}`; !strings.Contains(string(content), want) {
+		t.Errorf("Test file should contain %q, got:\n%s", want, content)
+	}
+}
+
+func TestGenerateGoldenTestRequiresInterpreter(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	if _, err := ws.GenerateGoldenTest("empty", t.TempDir()); err == nil {
+		t.Error("Expected an error for a session without code blocks")
+	}
+
+	if err := ws.AddCodeBlockWithOutput(`fmt.Print("ok")`, "ok"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if _, err := ws.GenerateGoldenTest("test_golden_ws", ""); err != nil {
+		t.Fatalf("Failed to generate golden test: %v", err)
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(ws.Path(), "go.mod"))
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if !strings.Contains(string(goMod), "github.com/traefik/yaegi v0.16.1") {
+		t.Error("Workspace go.mod should require the interpreter used by golden tests")
+	}
+}
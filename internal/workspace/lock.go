@@ -0,0 +1,122 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockFileName is the file locked while files shared by every gosh instance
+// using the workspace, such as go.mod and go.sum, are updated
+const lockFileName = ".gosh.lock"
+
+// lock takes the advisory workspace lock, waiting for other gosh instances
+// to release it, and returns the function releasing it
+func (w *Workspace) lock() (func(), error) {
+	return lockPath(filepath.Join(w.rootPath, lockFileName))
+}
+
+// lockPath takes an exclusive advisory lock on the file at path, creating it
+// when needed. The locked file is separate from the files it guards, which
+// are replaced on write.
+func lockPath(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock workspace: %w", err)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// sessionLockSuffix names the file locked while a gosh instance uses a session
+const sessionLockSuffix = ".lock"
+
+// sessionLockFile returns the path of the lock file of the session with the
+// given ID
+func (w *Workspace) sessionLockFile(id string) string {
+	return filepath.Join(w.internalPath, sessionFilePrefix+id+sessionLockSuffix)
+}
+
+// lockSession takes the advisory lock of a session without waiting, so that
+// two gosh instances never rewrite the same session file, and returns the
+// function releasing it
+func (w *Workspace) lockSession(id string) (func(), error) {
+	f, err := os.OpenFile(w.sessionLockFile(id), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session lock file: %w", err)
+	}
+	ok, err := tryLockFile(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock session %s: %w", id, err)
+	}
+	if !ok {
+		f.Close()
+		return nil, fmt.Errorf("session %s is in use by another gosh instance", id)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// SessionInUse reports whether another gosh instance holds the lock of the
+// session with the given ID
+func (w *Workspace) SessionInUse(id string) bool {
+	if id == w.sessionID {
+		return false
+	}
+	f, err := os.OpenFile(w.sessionLockFile(id), os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	ok, err := tryLockFile(f)
+	if ok {
+		unlockFile(f)
+	}
+	return err == nil && !ok
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
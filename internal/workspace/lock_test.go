@@ -0,0 +1,68 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), lockFileName)
+
+	unlock, err := lockPath(path)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		unlockOther, err := lockPath(path)
+		if err != nil {
+			t.Errorf("Failed to lock: %v", err)
+			close(acquired)
+			return
+		}
+		close(acquired)
+		unlockOther()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Lock should not be acquired while it is held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lock should be acquired once released")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "go.mod")
+
+	for _, content := range []string{"module a\n", "module b\n"} {
+		if err := writeFileAtomic(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(got) != content {
+			t.Errorf("Expected %q, got %q", content, got)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Temporary files should be renamed, found %d entries", len(entries))
+	}
+}
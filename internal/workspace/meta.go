@@ -0,0 +1,176 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const sessionMetaSuffix = ".json"
+
+// SessionMeta holds the user-provided metadata of a session, stored next to
+// its session file
+type SessionMeta struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Checkpoints maps checkpoint names to the number of blocks of the
+	// session when they were recorded
+	Checkpoints map[string]int `json:"checkpoints,omitempty"`
+}
+
+// metaFile returns the path of the metadata file of the session with the given ID
+func (w *Workspace) metaFile(id string) string {
+	return filepath.Join(w.internalPath, sessionFilePrefix+id+sessionMetaSuffix)
+}
+
+// sessionIDFromMetaFile returns the session ID of a metadata file name
+func sessionIDFromMetaFile(name string) (string, bool) {
+	if !strings.HasPrefix(name, sessionFilePrefix) || !strings.HasSuffix(name, sessionMetaSuffix) {
+		return "", false
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(name, sessionFilePrefix), sessionMetaSuffix)
+	return id, id != ""
+}
+
+// readMeta reads the metadata of a session, which is empty when the session
+// has none
+func (w *Workspace) readMeta(id string) (SessionMeta, error) {
+	var meta SessionMeta
+	content, err := os.ReadFile(w.metaFile(id))
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, fmt.Errorf("failed to read session metadata: %w", err)
+	}
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return meta, fmt.Errorf("failed to parse session metadata: %w", err)
+	}
+	return meta, nil
+}
+
+// writeMeta saves the metadata of the current session
+func (w *Workspace) writeMeta() error {
+	content, err := json.MarshalIndent(w.meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session metadata: %w", err)
+	}
+	if err := writeFileAtomic(w.metaFile(w.sessionID), append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write session metadata: %w", err)
+	}
+	return nil
+}
+
+// Meta returns the metadata of the current session
+func (w *Workspace) Meta() SessionMeta {
+	meta := w.meta
+	meta.Tags = slices.Clone(meta.Tags)
+	meta.Checkpoints = maps.Clone(meta.Checkpoints)
+	return meta
+}
+
+// SetName names the current session. Names are unique, so that sessions can
+// be addressed by name instead of ID.
+func (w *Workspace) SetName(name string) error {
+	if err := validateSessionName(name); err != nil {
+		return err
+	}
+	if id, err := w.findSessionByName(name); err == nil && id != w.sessionID {
+		return fmt.Errorf("session name %q is already used by session %s", name, id)
+	}
+
+	w.meta.Name = name
+	return w.writeMeta()
+}
+
+// SetDescription sets the description of the current session
+func (w *Workspace) SetDescription(description string) error {
+	w.meta.Description = strings.TrimSpace(description)
+	return w.writeMeta()
+}
+
+// AddTags attaches tags to the current session
+func (w *Workspace) AddTags(tags ...string) error {
+	for _, tag := range tags {
+		if tag == "" || strings.ContainsAny(tag, " \t,") {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+		if !slices.Contains(w.meta.Tags, tag) {
+			w.meta.Tags = append(w.meta.Tags, tag)
+		}
+	}
+	return w.writeMeta()
+}
+
+// RemoveTags detaches tags from the current session
+func (w *Workspace) RemoveTags(tags ...string) error {
+	w.meta.Tags = slices.DeleteFunc(w.meta.Tags, func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+	return w.writeMeta()
+}
+
+// ResolveSession returns the ID of the session addressed by ref, which is
+// either a session ID or a session name
+func (w *Workspace) ResolveSession(ref string) (string, error) {
+	if !validSessionID(ref) {
+		return "", fmt.Errorf("invalid session ID %q", ref)
+	}
+	if _, err := os.Stat(w.sessionFile(ref)); err == nil {
+		return ref, nil
+	}
+	if id, err := w.findSessionByName(ref); err == nil {
+		return id, nil
+	}
+	return "", fmt.Errorf("session %s not found", ref)
+}
+
+// findSessionByName returns the ID of the session with the given name.
+// Metadata of sessions that saved no block is ignored.
+func (w *Workspace) findSessionByName(name string) (string, error) {
+	if name == w.meta.Name {
+		return w.sessionID, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(w.internalPath, sessionFilePrefix+"*"+sessionMetaSuffix))
+	if err != nil {
+		return "", fmt.Errorf("failed to list session metadata: %w", err)
+	}
+	for _, path := range matches {
+		id, ok := sessionIDFromMetaFile(filepath.Base(path))
+		if !ok {
+			continue
+		}
+		if info, err := os.Stat(w.sessionFile(id)); err != nil || info.Size() == 0 {
+			continue
+		}
+		meta, err := w.readMeta(id)
+		if err == nil && meta.Name == name {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no session named %q", name)
+}
+
+// validateSessionName checks that a session name can be used on the command
+// line and cannot be mistaken for a path
+func validateSessionName(name string) error {
+	if name == "" {
+		return fmt.Errorf("session name cannot be empty")
+	}
+	for _, r := range name {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.", r)) {
+			return fmt.Errorf("invalid session name %q (use letters, digits, '-', '_' and '.')", name)
+		}
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("invalid session name %q", name)
+	}
+	return nil
+}
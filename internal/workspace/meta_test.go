@@ -0,0 +1,145 @@
+package workspace
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSessionMeta(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	ws.sessionID = "test_meta"
+
+	if err := ws.AddCodeBlock("x := 1"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if err := ws.SetName("test-meta-probe"); err != nil {
+		t.Fatalf("Failed to name session: %v", err)
+	}
+	if err := ws.SetDescription("  Probing the API  "); err != nil {
+		t.Fatalf("Failed to describe session: %v", err)
+	}
+	if err := ws.AddTags("http", "probe", "http"); err != nil {
+		t.Fatalf("Failed to tag session: %v", err)
+	}
+	if err := ws.RemoveTags("probe"); err != nil {
+		t.Fatalf("Failed to untag session: %v", err)
+	}
+
+	info, err := ws.Session(ws.sessionID)
+	if err != nil {
+		t.Fatalf("Failed to describe session: %v", err)
+	}
+	if info.Name != "test-meta-probe" || info.Description != "Probing the API" || !slices.Equal(info.Tags, []string{"http"}) {
+		t.Errorf("Unexpected metadata: %+v", info.SessionMeta)
+	}
+
+	// Another workspace addresses the session by name
+	other, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	other.sessionID = "test_meta_other"
+
+	id, err := other.ResolveSession("test-meta-probe")
+	if err != nil || id != ws.sessionID {
+		t.Errorf("Expected name to resolve to %s, got %s (%v)", ws.sessionID, id, err)
+	}
+	if id, err := other.ResolveSession(ws.sessionID); err != nil || id != ws.sessionID {
+		t.Errorf("Expected ID to resolve to itself, got %s (%v)", id, err)
+	}
+	if _, err := other.ResolveSession("test-meta-unknown"); err == nil {
+		t.Error("Expected an error resolving an unknown name")
+	}
+
+	if err := other.SetName("test-meta-probe"); err == nil {
+		t.Error("Expected an error reusing the name of another session")
+	}
+
+	// Resuming a session restores its metadata
+	if err := other.Resume(ws.sessionID, []string{"x := 1"}, []string{""}); err != nil {
+		t.Fatalf("Failed to resume session: %v", err)
+	}
+	if other.Meta().Name != "test-meta-probe" {
+		t.Errorf("Expected resumed session metadata, got %+v", other.Meta())
+	}
+}
+
+func TestSessionMetaCleanup(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	// Named but never saved
+	unsaved, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if err := unsaved.SetName("test-meta-unsaved"); err != nil {
+		t.Fatalf("Failed to name session: %v", err)
+	}
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if _, err := ws.ResolveSession("test-meta-unsaved"); err == nil {
+		t.Error("Expected the name of a session without code not to resolve")
+	}
+	if err := unsaved.Close(); err != nil {
+		t.Fatalf("Failed to close workspace: %v", err)
+	}
+	if _, err := os.Stat(unsaved.metaFile(unsaved.SessionID())); !os.IsNotExist(err) {
+		t.Error("Expected closing an unsaved session to remove its metadata")
+	}
+
+	// Clearing removes the metadata with the session file
+	if err := ws.AddCodeBlock("x := 1"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if err := ws.SetName("test-meta-cleared"); err != nil {
+		t.Fatalf("Failed to name session: %v", err)
+	}
+	if err := ws.Clear(); err != nil {
+		t.Fatalf("Failed to clear session: %v", err)
+	}
+	if _, err := os.Stat(ws.metaFile(ws.SessionID())); !os.IsNotExist(err) {
+		t.Error("Expected clearing to remove the session metadata")
+	}
+	if ws.Meta().Name != "" {
+		t.Errorf("Expected clearing to reset the metadata, got %+v", ws.Meta())
+	}
+
+	// Pruning removes metadata left without a session file
+	orphan := ws.metaFile("test_meta_orphan")
+	if err := os.WriteFile(orphan, []byte(`{"name": "test-meta-orphan"}`), 0644); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+	past := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(orphan, past, past); err != nil {
+		t.Fatalf("Failed to age metadata: %v", err)
+	}
+	if _, err := ws.PruneSessions(24 * time.Hour); err != nil {
+		t.Fatalf("Failed to prune sessions: %v", err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("Expected pruning to remove orphaned metadata")
+	}
+}
+
+func TestValidateSessionName(t *testing.T) {
+	for _, name := range []string{"api-probe", "v1.2_test"} {
+		if err := validateSessionName(name); err != nil {
+			t.Errorf("Expected %q to be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "..", "a/b", "with space", "café"} {
+		if err := validateSessionName(name); err == nil {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
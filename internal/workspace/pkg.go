@@ -0,0 +1,151 @@
+package workspace
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// PackagePath returns the default directory of the library package exported
+// from the session with the given name
+func (w *Workspace) PackagePath(name string) string {
+	return filepath.Join(w.rootPath, "pkg", name)
+}
+
+// PackageImportPath returns the import path of a package exported with the
+// given name into the workspace module
+func (w *Workspace) PackageImportPath(name string) string {
+	return WorkspaceModule + "/pkg/" + name
+}
+
+// GeneratePackage exports the functions, types, constants and variables
+// declared in the session as an importable Go package. The package is
+// written to dir, or to PackagePath(name) when dir is empty, and the
+// directory actually used is returned.
+func (w *Workspace) GeneratePackage(name, dir string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("package name cannot be empty")
+	}
+	pkgName := packageName(name)
+	if pkgName == "" {
+		return "", fmt.Errorf("invalid package name %q", name)
+	}
+	if dir == "" {
+		dir = w.PackagePath(name)
+	}
+
+	src := w.parseSession()
+	if len(src.decls) == 0 {
+		return "", fmt.Errorf("session has no declarations to export")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create package directory: %w", err)
+	}
+
+	pkgFile := filepath.Join(dir, pkgName+".go")
+	if err := os.WriteFile(pkgFile, renderPackage(pkgName, w.sessionID, src), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", filepath.Base(pkgFile), err)
+	}
+
+	return dir, nil
+}
+
+// renderPackage renders the declarations of the session as a library package
+func renderPackage(pkgName, sessionID string, src *cliSource) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Package %s contains the declarations exported from gosh session %s.\n", pkgName, sessionID)
+	fmt.Fprintf(&b, "package %s\n\n", pkgName)
+
+	// Statements are not exported, so only keep the imports the
+	// declarations use. fmt is available in every session without import.
+	used := usedPackages(src.decls)
+	var imports []string
+	for _, imp := range append([]string{`"fmt"`}, src.imports...) {
+		if name := importName(imp); used[name] || name == "_" || name == "." {
+			imports = append(imports, imp)
+		}
+	}
+	if len(imports) > 0 {
+		renderImports(&b, nil, imports)
+	}
+
+	for _, decl := range src.decls {
+		b.WriteString(decl.text)
+		b.WriteString("\n\n")
+	}
+
+	return formatSource(b.String())
+}
+
+// usedPackages returns the identifiers used as qualifier of a selector in
+// the given items, which includes the names of the imported packages they use
+func usedPackages(items []sourceItem) map[string]bool {
+	used := make(map[string]bool)
+	for _, item := range items {
+		if item.node == nil {
+			continue
+		}
+		ast.Inspect(item.node, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok {
+					used[ident.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	return used
+}
+
+// importName returns the name an import spec binds in the importing file.
+// Without an explicit name the package name is guessed from the import path.
+func importName(spec string) string {
+	quote := strings.IndexAny(spec, "\"`")
+	if quote > 0 {
+		return strings.TrimSpace(spec[:quote])
+	}
+
+	elems := strings.Split(strings.Trim(spec, "\"`"), "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	return strings.ReplaceAll(name, "-", "")
+}
+
+// isMajorVersion reports whether a path element is a major version suffix
+// such as v2
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
+	}
+	for _, r := range elem[1:] {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// packageName derives a valid Go package name from a user-provided name
+func packageName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	pkg := b.String()
+	if pkg != "" && unicode.IsDigit(rune(pkg[0])) {
+		pkg = "pkg" + pkg
+	}
+	return pkg
+}
@@ -0,0 +1,92 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGeneratePackage(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	blocks := []string{
+		"import (\n\t\"os\"\n\t\"strings\"\n)",
+		"// Shout returns s in upper case\nfunc Shout(s string) string {\n\treturn strings.ToUpper(s)\n}",
+		"type Point struct {\n\tX, Y int\n}",
+		"fmt.Println(Shout(\"hi\"), os.Args)",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	dir, err := ws.GeneratePackage("test-helpers", t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to generate package: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "testhelpers.go"))
+	if err != nil {
+		t.Fatalf("Failed to read package file: %v", err)
+	}
+	pkg := string(content)
+
+	for _, want := range []string{
+		"// Package testhelpers contains the declarations exported from gosh session",
+		"package testhelpers",
+		"import \"strings\"",
+		"func Shout(s string) string {",
+		"type Point struct {",
+	} {
+		if !strings.Contains(pkg, want) {
+			t.Errorf("Package should contain %q, got:\n%s", want, pkg)
+		}
+	}
+
+	for _, unwanted := range []string{`"os"`, `"fmt"`, "fmt.Println"} {
+		if strings.Contains(pkg, unwanted) {
+			t.Errorf("Package should not contain %q, got:\n%s", unwanted, pkg)
+		}
+	}
+}
+
+func TestGeneratePackageWithoutDeclarations(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	if err := ws.AddCodeBlock(`fmt.Println("nothing to export")`); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	if _, err := ws.GeneratePackage("empty", t.TempDir()); err == nil {
+		t.Error("Expected an error when the session has no declarations")
+	}
+}
+
+func TestImportName(t *testing.T) {
+	tests := map[string]string{
+		`"strings"`:                    "strings",
+		`str "strings"`:                "str",
+		`"net/http"`:                   "http",
+		`"gopkg.in/yaml.v3"`:           "yaml",
+		`"github.com/mattn/go-isatty"`: "isatty",
+		`"github.com/go-chi/chi/v5"`:   "chi",
+		`_ "embed"`:                    "_",
+	}
+	for spec, want := range tests {
+		if got := importName(spec); got != want {
+			t.Errorf("importName(%s) = %q, want %q", spec, got, want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// rcFileName is the startup file evaluated into every interpreter
+const rcFileName = "goshrc.go"
+
+// StartupBlock is a piece of the startup file evaluated on its own
+type StartupBlock struct {
+	Code string
+	Line int // line of the startup file where Code starts, 1-based
+}
+
+// RCPath returns the path of the startup file of the workspace
+func (w *Workspace) RCPath() string {
+	return filepath.Join(w.rootPath, rcFileName)
+}
+
+// StartupBlocks reads the startup file and splits it into blocks the
+// interpreter evaluates one at a time: imports and declarations apart from
+// statements. A leading package clause, which lets editors treat the file as
+// Go, is skipped. There are no blocks when the file does not exist.
+func (w *Workspace) StartupBlocks() ([]StartupBlock, error) {
+	content, err := os.ReadFile(w.RCPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read startup file: %w", err)
+	}
+	return splitStartup(string(content)), nil
+}
+
+// splitStartup splits the content of a startup file into blocks
+func splitStartup(content string) []StartupBlock {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	// Blank the package clause rather than removing it, to keep line numbers
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "package ") {
+			lines[i] = ""
+		}
+		break
+	}
+
+	var blocks []StartupBlock
+	for _, seg := range splitSegments(strings.Join(lines, "\n")) {
+		// Segments start at the end of the previous one: skip to their code
+		text := strings.TrimLeft(seg.text, "\n")
+		line := seg.line + len(seg.text) - len(text)
+		if text = strings.TrimSpace(text); text != "" {
+			blocks = append(blocks, StartupBlock{Code: text, Line: line})
+		}
+	}
+	return blocks
+}
+
+// StartupNamesUsed returns the names declared by the startup file that the
+// session refers to without declaring them itself. Exported code does not
+// include the startup file, so it does not compile when it uses them.
+func (w *Workspace) StartupNamesUsed() ([]string, error) {
+	blocks, err := w.StartupBlocks()
+	if err != nil {
+		return nil, err
+	}
+	startup := make(map[string]bool)
+	for _, block := range blocks {
+		items, err := parseBlock(block.Code)
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			defines, _ := stepNames(item.node)
+			for _, name := range defines {
+				startup[name] = true
+			}
+		}
+	}
+
+	declared := make(map[string]bool)
+	used := make(map[string]bool)
+	for _, block := range w.codeBlocks {
+		items, err := parseBlock(block.code)
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			defines, uses := stepNames(item.node)
+			for _, name := range defines {
+				declared[name] = true
+			}
+			for _, name := range uses {
+				used[name] = true
+			}
+		}
+	}
+
+	var names []string
+	for name := range used {
+		if startup[name] && !declared[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
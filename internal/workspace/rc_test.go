@@ -0,0 +1,71 @@
+package workspace
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestStartupBlocks(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	blocks, err := ws.StartupBlocks()
+	if err != nil || blocks != nil {
+		t.Fatalf("Expected no blocks without a startup file, got %v (%v)", blocks, err)
+	}
+
+	content := "package rc\n\n// helpers\nimport \"strings\"\n\nfunc Up(s string) string { return strings.ToUpper(s) }\n\nx := 1\ny := x + 1\n\nconst z = 3\n"
+	if err := os.WriteFile(ws.RCPath(), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write startup file: %v", err)
+	}
+
+	blocks, err = ws.StartupBlocks()
+	if err != nil {
+		t.Fatalf("Failed to read startup file: %v", err)
+	}
+	want := []StartupBlock{
+		{Code: "// helpers\nimport \"strings\"", Line: 3},
+		{Code: "func Up(s string) string { return strings.ToUpper(s) }", Line: 6},
+		{Code: "x := 1\ny := x + 1", Line: 8},
+		{Code: "const z = 3", Line: 11},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("Expected %d blocks, got %q", len(want), blocks)
+	}
+	for i := range want {
+		if blocks[i] != want[i] {
+			t.Errorf("Block %d: expected %+v, got %+v", i, want[i], blocks[i])
+		}
+	}
+}
+
+func TestStartupNamesUsed(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	content := "func Up(s string) string { return s }\n\nfunc Down(s string) string { return s }\n\nlimit := 3\n"
+	if err := os.WriteFile(ws.RCPath(), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write startup file: %v", err)
+	}
+	for _, block := range []string{"fmt.Println(Up(\"a\"), limit)", "func Down(s string) string { return s + \"!\" }\nfmt.Println(Down(\"b\"))"} {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	names, err := ws.StartupNamesUsed()
+	if err != nil {
+		t.Fatalf("Failed to find startup names: %v", err)
+	}
+	if !slices.Equal(names, []string{"Up", "limit"}) {
+		t.Errorf("Expected [Up limit], got %v", names)
+	}
+}
@@ -0,0 +1,348 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sessionFilePrefix = "session_"
+	sessionFileSuffix = ".go"
+
+	// sessionHeader starts every session file
+	sessionHeader = "package internal\n\nimport (\n\t\"fmt\"\n)\n\n"
+	// blockMarker precedes each code block in a session file
+	blockMarker = "// Block\n"
+)
+
+// sessionFile returns the path of the file holding the session with the given ID
+func (w *Workspace) sessionFile(id string) string {
+	return filepath.Join(w.internalPath, sessionFilePrefix+id+sessionFileSuffix)
+}
+
+// renderSession renders the session file content for the given code blocks,
+// where declarations superseded by a later block are commented out
+func renderSession(blocks []codeBlock) string {
+	var b strings.Builder
+	b.WriteString(sessionHeader)
+	for _, code := range supersededCode(blocks) {
+		b.WriteString(blockMarker)
+		b.WriteString(code)
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+// parseSessionFile splits the content of a session file into its code
+// blocks, restoring the declarations superseded by later blocks
+func parseSessionFile(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	start := strings.Index(content, blockMarker)
+	if start < 0 {
+		return nil
+	}
+	content = content[start+len(blockMarker):]
+
+	var blocks []string
+	for _, block := range strings.Split(content, "\n\n"+blockMarker) {
+		if block = strings.TrimSpace(restoreSuperseded(block)); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// validSessionID reports whether id can name a session file
+func validSessionID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`) && id != "." && id != ".."
+}
+
+// LoadSession reads the code blocks saved in the file of the session with
+// the given ID, in evaluation order
+func (w *Workspace) LoadSession(id string) ([]string, error) {
+	if !validSessionID(id) {
+		return nil, fmt.Errorf("invalid session ID %q", id)
+	}
+
+	content, err := os.ReadFile(w.sessionFile(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("session %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+	return parseSessionFile(string(content)), nil
+}
+
+// LatestSessionID returns the ID of the most recently modified saved
+// session other than the current one, skipping those other gosh instances
+// are using
+func (w *Workspace) LatestSessionID() (string, error) {
+	entries, err := os.ReadDir(w.internalPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read internal directory: %w", err)
+	}
+
+	var latest string
+	var latestTime int64
+	for _, entry := range entries {
+		id, ok := sessionIDFromFile(entry.Name())
+		if !ok || entry.IsDir() || id == w.sessionID || w.SessionInUse(id) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Size() == 0 {
+			// Reserved by a session that saved no block
+			continue
+		}
+		if t := info.ModTime().UnixNano(); latest == "" || t > latestTime {
+			latest, latestTime = id, t
+		}
+	}
+
+	if latest == "" {
+		return "", fmt.Errorf("no saved session to resume")
+	}
+	return latest, nil
+}
+
+// sessionIDFromFile returns the session ID of a session file name
+func sessionIDFromFile(name string) (string, bool) {
+	if !strings.HasPrefix(name, sessionFilePrefix) || !strings.HasSuffix(name, sessionFileSuffix) {
+		return "", false
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(name, sessionFilePrefix), sessionFileSuffix)
+	return id, id != ""
+}
+
+// Resume continues the session with the given ID: its blocks, replayed by
+// the caller with the given outputs, replace the current ones and new blocks
+// are appended to its session file
+func (w *Workspace) Resume(id string, blocks, outputs []string) error {
+	if !validSessionID(id) {
+		return fmt.Errorf("invalid session ID %q", id)
+	}
+	if len(outputs) != len(blocks) {
+		return fmt.Errorf("expected %d block outputs, got %d", len(blocks), len(outputs))
+	}
+
+	meta, err := w.readMeta(id)
+	if err != nil {
+		return err
+	}
+	if id != w.sessionID {
+		unlock, err := w.lockSession(id)
+		if err != nil {
+			return err
+		}
+		if err := w.switchSession(id, unlock); err != nil {
+			return err
+		}
+	}
+
+	w.meta = meta
+	w.codeBlocks = make([]codeBlock, len(blocks))
+	for i, code := range blocks {
+		w.codeBlocks[i] = codeBlock{code: code, output: outputs[i]}
+	}
+	return nil
+}
+
+// Fork continues the current session under a new ID and name: its blocks and
+// metadata are copied to new files, and the files of the original session
+// are left as they are, except for the session file it reserved if it saved
+// no block
+func (w *Workspace) Fork(name string) error {
+	if err := validateSessionName(name); err != nil {
+		return err
+	}
+	if id, err := w.findSessionByName(name); err == nil {
+		return fmt.Errorf("session name %q is already used by session %s", name, id)
+	}
+
+	id, err := newSessionID(w.internalPath)
+	if err != nil {
+		return err
+	}
+	unlock, err := w.lockSession(id)
+	if err != nil {
+		os.Remove(w.sessionFile(id))
+		return err
+	}
+	if err := writeFileAtomic(w.sessionFile(id), []byte(renderSession(w.codeBlocks)), 0644); err != nil {
+		unlock()
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	originalID, originalMeta := w.sessionID, w.meta
+	w.sessionID = id
+	w.meta = w.Meta()
+	w.meta.Name = name
+	if err := w.writeMeta(); err != nil {
+		os.Remove(w.sessionFile(id))
+		unlock()
+		w.sessionID, w.meta = originalID, originalMeta
+		return err
+	}
+	w.sessionID = originalID
+	return w.switchSession(id, unlock)
+}
+
+// SessionInfo describes a session saved in the internal directory
+type SessionInfo struct {
+	SessionMeta
+	ID       string
+	Modified time.Time // last time a block was saved
+	Blocks   int       // number of code blocks
+	Size     int64     // size of the session file in bytes
+}
+
+// Sessions lists the saved sessions, most recently modified first. Session
+// files reserved by sessions that saved no block are left out.
+func (w *Workspace) Sessions() ([]SessionInfo, error) {
+	entries, err := os.ReadDir(w.internalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read internal directory: %w", err)
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		id, ok := sessionIDFromFile(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := w.Session(id)
+		if err != nil || info.Size == 0 {
+			continue
+		}
+		sessions = append(sessions, info)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Modified.After(sessions[j].Modified)
+	})
+	return sessions, nil
+}
+
+// Session describes the saved session with the given ID
+func (w *Workspace) Session(id string) (SessionInfo, error) {
+	if !validSessionID(id) {
+		return SessionInfo{}, fmt.Errorf("invalid session ID %q", id)
+	}
+
+	path := w.sessionFile(id)
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return SessionInfo{}, fmt.Errorf("session %s not found", id)
+	}
+	if err != nil {
+		return SessionInfo{}, fmt.Errorf("failed to stat session file: %w", err)
+	}
+
+	blocks, err := w.LoadSession(id)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+
+	meta, err := w.readMeta(id)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+
+	return SessionInfo{
+		SessionMeta: meta,
+		ID:          id,
+		Modified:    stat.ModTime(),
+		Blocks:      len(blocks),
+		Size:        stat.Size(),
+	}, nil
+}
+
+// RemoveSession deletes the file of a saved session. The current session
+// cannot be removed; use Clear instead. Neither can a session another gosh
+// instance is using.
+func (w *Workspace) RemoveSession(id string) error {
+	if !validSessionID(id) {
+		return fmt.Errorf("invalid session ID %q", id)
+	}
+	if id == w.sessionID {
+		return fmt.Errorf("cannot remove the current session")
+	}
+	if w.SessionInUse(id) {
+		return fmt.Errorf("session %s is in use by another gosh instance", id)
+	}
+
+	if err := os.Remove(w.sessionFile(id)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("session %s not found", id)
+		}
+		return fmt.Errorf("failed to remove session file: %w", err)
+	}
+	if err := os.Remove(w.metaFile(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session metadata: %w", err)
+	}
+	if err := os.Remove(w.sessionLockFile(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session lock file: %w", err)
+	}
+	return nil
+}
+
+// PruneSessions removes the saved sessions not modified for longer than
+// maxAge, except the current one and those other gosh instances are using,
+// and returns the IDs of those removed.
+// Session files reserved that long ago by sessions that saved no block, as
+// when gosh was killed, and metadata left without a session file are removed
+// too.
+func (w *Workspace) PruneSessions(maxAge time.Duration) ([]string, error) {
+	cutoff := time.Now().Add(-maxAge)
+	if err := w.pruneOrphans(cutoff); err != nil {
+		return nil, err
+	}
+
+	sessions, err := w.Sessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, session := range sessions {
+		if session.ID == w.sessionID || !session.Modified.Before(cutoff) || w.SessionInUse(session.ID) {
+			continue
+		}
+		if err := w.RemoveSession(session.ID); err != nil {
+			return removed, err
+		}
+		removed = append(removed, session.ID)
+	}
+	return removed, nil
+}
+
+// pruneOrphans removes the empty session files of other sessions, and the
+// metadata without a session file, last modified before cutoff
+func (w *Workspace) pruneOrphans(cutoff time.Time) error {
+	entries, err := os.ReadDir(w.internalPath)
+	if err != nil {
+		return fmt.Errorf("failed to read internal directory: %w", err)
+	}
+	for _, entry := range entries {
+		id, ok := sessionIDFromFile(entry.Name())
+		if !ok {
+			id, ok = sessionIDFromMetaFile(entry.Name())
+		}
+		if !ok || entry.IsDir() || id == w.sessionID || w.SessionInUse(id) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := w.releaseSessionID(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
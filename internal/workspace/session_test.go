@@ -0,0 +1,310 @@
+package workspace
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseSessionFile(t *testing.T) {
+	blocks := []codeBlock{
+		{code: "x := 1"},
+		{code: "func add(a, b int) int {\n\treturn a + b\n}"},
+		{code: "fmt.Println(add(x, 2))"},
+	}
+
+	got := parseSessionFile(renderSession(blocks))
+	if len(got) != len(blocks) {
+		t.Fatalf("Expected %d blocks, got %d: %q", len(blocks), len(got), got)
+	}
+	for i, block := range blocks {
+		if got[i] != block.code {
+			t.Errorf("Block %d: expected %q, got %q", i, block.code, got[i])
+		}
+	}
+
+	if got := parseSessionFile(sessionHeader); len(got) != 0 {
+		t.Errorf("Expected no blocks in an empty session, got %q", got)
+	}
+}
+
+func TestResume(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	ws.sessionID = "test_resume"
+
+	for _, block := range []string{"x := 1", "fmt.Println(x)"} {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	resumed, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	blocks, err := resumed.LoadSession(ws.sessionID)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	if len(blocks) != 2 || blocks[1] != "fmt.Println(x)" {
+		t.Fatalf("Unexpected blocks: %q", blocks)
+	}
+
+	if err := resumed.Resume(ws.sessionID, blocks, []string{"", "1\n"}); err != nil {
+		t.Fatalf("Failed to resume session: %v", err)
+	}
+	if resumed.SessionID() != ws.sessionID {
+		t.Errorf("Expected session ID %s, got %s", ws.sessionID, resumed.SessionID())
+	}
+
+	// New blocks are appended to the resumed session file
+	if err := resumed.AddCodeBlock("x++"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	blocks, err = ws.LoadSession(ws.sessionID)
+	if err != nil {
+		t.Fatalf("Failed to reload session: %v", err)
+	}
+	if len(blocks) != 3 || blocks[2] != "x++" {
+		t.Errorf("Expected the new block appended, got %q", blocks)
+	}
+}
+
+func TestFork(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if err := ws.AddCodeBlock("setup := 1"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if err := ws.SetName("base"); err != nil {
+		t.Fatalf("Failed to name session: %v", err)
+	}
+	if err := ws.AddTags("demo"); err != nil {
+		t.Fatalf("Failed to tag session: %v", err)
+	}
+	original := ws.SessionID()
+
+	if err := ws.Fork("base"); err == nil {
+		t.Error("Expected an error forking into a used name")
+	}
+	if err := ws.Fork("bad name"); err == nil {
+		t.Error("Expected an error forking into an invalid name")
+	}
+	if ws.SessionID() != original {
+		t.Fatal("A failed fork should keep the session")
+	}
+
+	if err := ws.Fork("approach-a"); err != nil {
+		t.Fatalf("Failed to fork session: %v", err)
+	}
+	if ws.SessionID() == original {
+		t.Fatal("Expected a new session ID after forking")
+	}
+	if meta := ws.Meta(); meta.Name != "approach-a" || !slices.Equal(meta.Tags, []string{"demo"}) {
+		t.Errorf("Expected the metadata to be copied with the new name, got %+v", meta)
+	}
+
+	// New blocks only go to the fork
+	if err := ws.AddCodeBlock("a := setup + 1"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	blocks, err := ws.LoadSession(original)
+	if err != nil {
+		t.Fatalf("Failed to load original session: %v", err)
+	}
+	if !slices.Equal(blocks, []string{"setup := 1"}) {
+		t.Errorf("The original session should be untouched, got %q", blocks)
+	}
+	if meta, err := ws.readMeta(original); err != nil || meta.Name != "base" {
+		t.Errorf("The original metadata should be untouched, got %+v, %v", meta, err)
+	}
+	blocks, err = ws.LoadSession(ws.SessionID())
+	if err != nil {
+		t.Fatalf("Failed to load forked session: %v", err)
+	}
+	if !slices.Equal(blocks, []string{"setup := 1", "a := setup + 1"}) {
+		t.Errorf("Unexpected forked blocks %q", blocks)
+	}
+	if id, err := ws.ResolveSession("base"); err != nil || id != original {
+		t.Errorf("Expected base to resolve to %s, got %s, %v", original, id, err)
+	}
+}
+
+func TestLoadSessionErrors(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	for _, id := range []string{"", "../go", "does_not_exist"} {
+		if _, err := ws.LoadSession(id); err == nil {
+			t.Errorf("Expected an error loading session %q", id)
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	ws.sessionID = "test_sessions"
+
+	for _, block := range []string{"x := 1", "fmt.Println(x)"} {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	sessions, err := ws.Sessions()
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+
+	var found *SessionInfo
+	for i := range sessions {
+		if sessions[i].ID == ws.sessionID {
+			found = &sessions[i]
+		}
+		if i > 0 && sessions[i].Modified.After(sessions[i-1].Modified) {
+			t.Error("Sessions should be sorted most recent first")
+		}
+	}
+	if found == nil {
+		t.Fatalf("Session %s not listed", ws.sessionID)
+	}
+	if found.Blocks != 2 {
+		t.Errorf("Expected 2 blocks, got %d", found.Blocks)
+	}
+	if found.Size != int64(len(renderSession(ws.codeBlocks))) {
+		t.Errorf("Unexpected session size %d", found.Size)
+	}
+}
+
+func TestSessionInUse(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	active, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if err := active.AddCodeBlock("x := 1"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	id := active.SessionID()
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if !ws.SessionInUse(id) {
+		t.Fatal("Expected the session of another workspace to be in use")
+	}
+	if latest, err := ws.LatestSessionID(); err == nil {
+		t.Errorf("Expected no session to resume, got %s", latest)
+	}
+	if err := ws.Resume(id, []string{"x := 1"}, []string{""}); err == nil {
+		t.Error("Expected an error resuming a session in use")
+	}
+	if err := ws.RemoveSession(id); err == nil {
+		t.Error("Expected an error removing a session in use")
+	}
+	if removed, err := ws.PruneSessions(0); err != nil || len(removed) != 0 {
+		t.Errorf("Expected a session in use to be kept, got %v (%v)", removed, err)
+	}
+
+	// Once the other workspace is closed, the session can be resumed
+	if err := active.Close(); err != nil {
+		t.Fatalf("Failed to close workspace: %v", err)
+	}
+	if latest, err := ws.LatestSessionID(); err != nil || latest != id {
+		t.Fatalf("Expected to resume %s, got %q (%v)", id, latest, err)
+	}
+	if err := ws.Resume(id, []string{"x := 1"}, []string{""}); err != nil {
+		t.Fatalf("Failed to resume session: %v", err)
+	}
+
+	other, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if !other.SessionInUse(id) {
+		t.Error("Expected the resumed session to be in use")
+	}
+	if err := ws.Close(); err != nil {
+		t.Fatalf("Failed to close workspace: %v", err)
+	}
+	if other.SessionInUse(id) {
+		t.Error("Expected closing to release the session")
+	}
+}
+
+func TestRemoveAndPruneSessions(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	ws.sessionID = "test_prune_current"
+	if err := ws.AddCodeBlock("x := 1"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	old := ws.sessionFile("test_prune_old")
+	recent := ws.sessionFile("test_prune_recent")
+	for _, path := range []string{old, recent} {
+		if err := os.WriteFile(path, []byte(renderSession(ws.codeBlocks)), 0644); err != nil {
+			t.Fatalf("Failed to write session file: %v", err)
+		}
+	}
+	// Reserved by a session killed before saving a block
+	reserved := ws.sessionFile("test_prune_reserved")
+	if err := os.WriteFile(reserved, nil, 0644); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+	past := time.Now().Add(-48 * time.Hour)
+	for _, path := range []string{old, reserved, ws.sessionFile(ws.sessionID)} {
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatalf("Failed to age session file: %v", err)
+		}
+	}
+
+	removed, err := ws.PruneSessions(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to prune sessions: %v", err)
+	}
+	if !slices.Contains(removed, "test_prune_old") {
+		t.Errorf("Expected test_prune_old to be pruned, got %v", removed)
+	}
+	if slices.Contains(removed, ws.sessionID) || slices.Contains(removed, "test_prune_recent") {
+		t.Errorf("Current and recent sessions should be kept, got %v", removed)
+	}
+	if _, err := os.Stat(reserved); !os.IsNotExist(err) {
+		t.Error("Expected the stale reservation to be pruned")
+	}
+
+	if err := ws.RemoveSession(ws.sessionID); err == nil {
+		t.Error("Removing the current session should fail")
+	}
+	if err := ws.RemoveSession("test_prune_recent"); err != nil {
+		t.Errorf("Failed to remove session: %v", err)
+	}
+	if _, err := ws.Session("test_prune_recent"); err == nil {
+		t.Error("Removed session should not be found")
+	}
+}
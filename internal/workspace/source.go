@@ -0,0 +1,238 @@
+package workspace
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// itemKind classifies a top-level element of a code block
+type itemKind int
+
+const (
+	importItem itemKind = iota
+	declItem
+	stmtItem
+)
+
+// sourceItem is a top-level import, declaration or statement of a code block
+type sourceItem struct {
+	kind  itemKind
+	node  ast.Node  // *ast.GenDecl for imports, ast.Decl or ast.Stmt otherwise
+	text  string    // source text including leading and trailing comments
+	line  int       // line of the block where text starts, 1-based
+	block int       // index of the session block holding the item
+	pos   token.Pos // position of the start of text, to locate nodes in it
+}
+
+// segment is a run of lines of a code block that are either all package-level
+// declarations or all statements
+type segment struct {
+	decl bool
+	text string
+	line int
+}
+
+const (
+	declPrefix = "package p\n"
+	stmtPrefix = "package p\nfunc _() {\n"
+	stmtSuffix = "\n}\n"
+)
+
+// parseBlock splits a code block into imports, declarations and statements.
+// A block typed at the prompt may freely mix both, which Go itself only
+// allows inside a function body or at package level respectively.
+func parseBlock(code string) ([]sourceItem, error) {
+	var items []sourceItem
+	for _, seg := range splitSegments(code) {
+		segItems, err := parseSegment(seg)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, segItems...)
+	}
+	return items, nil
+}
+
+// splitSegments cuts code at lines starting a package-level declaration and
+// at lines where statements resume after one
+func splitSegments(code string) []segment {
+	src := []byte(code)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	type tok struct {
+		tok    token.Token
+		offset int
+		end    int
+		line   int
+	}
+	var toks []tok
+	var kinds []token.Token
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+	for {
+		pos, t, lit := s.Scan()
+		if t == token.EOF {
+			break
+		}
+		if t == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		offset := file.Offset(pos)
+		length := len(lit)
+		if length == 0 {
+			length = len(t.String())
+		}
+		toks = append(toks, tok{tok: t, offset: offset, end: offset + length, line: file.Line(pos)})
+		kinds = append(kinds, t)
+	}
+
+	var segments []segment
+	start, startLine, inDecl := 0, 1, false
+	depth, lastLine := 0, 0
+	for idx, t := range toks {
+		if t.line != lastLine && depth == 0 && idx > 0 {
+			isDecl := startsDecl(kinds[idx:])
+			if isDecl || inDecl {
+				// The boundary is the end of the line holding the previous
+				// token, so comments above a declaration stay attached to it
+				boundary := lineEnd(src, toks[idx-1].end)
+				if text := strings.TrimSpace(string(src[start:boundary])); text != "" {
+					segments = append(segments, segment{decl: inDecl, text: string(src[start:boundary]), line: startLine})
+				}
+				start, startLine = boundary, file.Line(file.Pos(boundary))
+				inDecl = isDecl
+			}
+		} else if idx == 0 {
+			inDecl = startsDecl(kinds)
+		}
+		lastLine = t.line
+
+		switch t.tok {
+		case token.LPAREN, token.LBRACE, token.LBRACK:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACK:
+			depth--
+		}
+	}
+	if strings.TrimSpace(string(src[start:])) != "" {
+		segments = append(segments, segment{decl: inDecl, text: string(src[start:]), line: startLine})
+	}
+	return segments
+}
+
+// startsDecl reports whether the tokens starting at a line begin a
+// package-level declaration rather than a statement
+func startsDecl(toks []token.Token) bool {
+	if len(toks) == 0 {
+		return false
+	}
+	switch toks[0] {
+	case token.IMPORT, token.TYPE, token.CONST, token.VAR:
+		return true
+	case token.FUNC:
+		if len(toks) < 2 {
+			return false
+		}
+		if toks[1] == token.IDENT {
+			return true
+		}
+		if toks[1] != token.LPAREN {
+			return false
+		}
+		// Either a method receiver or a function literal: a method has a
+		// name right after the receiver list
+		depth := 0
+		for i := 1; i < len(toks); i++ {
+			switch toks[i] {
+			case token.LPAREN:
+				depth++
+			case token.RPAREN:
+				depth--
+				if depth == 0 {
+					return i+1 < len(toks) && toks[i+1] == token.IDENT
+				}
+			}
+		}
+	}
+	return false
+}
+
+// lineEnd returns the offset just past the newline ending the line that
+// contains offset, or the end of src
+func lineEnd(src []byte, offset int) int {
+	for i := offset; i < len(src); i++ {
+		if src[i] == '\n' {
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// parseSegment parses a segment and returns its items with their source
+func parseSegment(seg segment) ([]sourceItem, error) {
+	prefix, suffix := declPrefix, ""
+	if !seg.decl {
+		prefix, suffix = stmtPrefix, stmtSuffix
+	}
+	src := prefix + seg.text + suffix
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse code block: %w", err)
+	}
+
+	type node struct {
+		kind itemKind
+		n    ast.Node
+	}
+	var nodes []node
+	if seg.decl {
+		for _, decl := range file.Decls {
+			kind := declItem
+			if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+				kind = importItem
+			}
+			nodes = append(nodes, node{kind: kind, n: decl})
+		}
+	} else {
+		body := file.Decls[0].(*ast.FuncDecl).Body
+		for _, stmt := range body.List {
+			nodes = append(nodes, node{kind: stmtItem, n: stmt})
+		}
+	}
+
+	items := make([]sourceItem, 0, len(nodes))
+	limit := len(src) - len(suffix)
+	prev := len(prefix)
+	for i, nd := range nodes {
+		end := lineEnd([]byte(src[:limit]), fset.Position(nd.n.End()).Offset)
+		if i < len(nodes)-1 {
+			// Keep a trailing comment on the same line but never swallow the
+			// next item when both share a line
+			if next := fset.Position(nodes[i+1].n.Pos()).Offset; next < end {
+				end = fset.Position(nd.n.End()).Offset
+			}
+		} else {
+			end = limit
+		}
+
+		raw := src[prev:end]
+		text := strings.TrimSpace(raw)
+		leading := raw[:strings.Index(raw, text)]
+		items = append(items, sourceItem{
+			kind: nd.kind,
+			node: nd.n,
+			text: text,
+			line: seg.line + strings.Count(src[len(prefix):prev], "\n") + strings.Count(leading, "\n"),
+			pos:  file.FileStart + token.Pos(prev+len(leading)),
+		})
+		prev = end
+	}
+	return items, nil
+}
@@ -0,0 +1,70 @@
+package workspace
+
+import (
+	"testing"
+)
+
+func TestParseBlock(t *testing.T) {
+	code := `import "strings"
+
+// Up converts s to upper case
+func Up(s string) string {
+	return strings.ToUpper(s)
+}
+fmt.Println(Up("a"))
+func() {
+}()`
+
+	items, err := parseBlock(code)
+	if err != nil {
+		t.Fatalf("Failed to parse block: %v", err)
+	}
+
+	want := []struct {
+		kind itemKind
+		line int
+	}{
+		{importItem, 1},
+		{declItem, 3},
+		{stmtItem, 7},
+		{stmtItem, 8},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("Expected %d items, got %d", len(want), len(items))
+	}
+	for i, w := range want {
+		if items[i].kind != w.kind {
+			t.Errorf("Item %d: expected kind %d, got %d", i, w.kind, items[i].kind)
+		}
+		if items[i].line != w.line {
+			t.Errorf("Item %d: expected line %d, got %d", i, w.line, items[i].line)
+		}
+	}
+
+	if items[1].text != "// Up converts s to upper case\nfunc Up(s string) string {\n\treturn strings.ToUpper(s)\n}" {
+		t.Errorf("Declaration should keep its doc comment, got: %q", items[1].text)
+	}
+}
+
+func TestParseBlockTrailingComment(t *testing.T) {
+	items, err := parseBlock("x := 42 //gosh:flag\ny := x")
+	if err != nil {
+		t.Fatalf("Failed to parse block: %v", err)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(items))
+	}
+	if items[0].text != "x := 42 //gosh:flag" {
+		t.Errorf("Trailing comment should stay with its statement, got: %q", items[0].text)
+	}
+	if items[1].text != "y := x" {
+		t.Errorf("Unexpected second statement: %q", items[1].text)
+	}
+}
+
+func TestParseBlockInvalid(t *testing.T) {
+	if _, err := parseBlock("this is not valid go code"); err == nil {
+		t.Error("Expected an error for invalid code")
+	}
+}
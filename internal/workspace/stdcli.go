@@ -0,0 +1,110 @@
+package workspace
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stdFlagFuncs maps the supported variable types to their flag package setter
+var stdFlagFuncs = map[string]string{
+	"string":  "StringVar",
+	"bool":    "BoolVar",
+	"int":     "IntVar",
+	"int64":   "Int64Var",
+	"uint":    "UintVar",
+	"uint64":  "Uint64Var",
+	"float64": "Float64Var",
+}
+
+// renderStdMain renders the main.go of a CLI tool that only uses the flag
+// package of the standard library
+func renderStdMain(name, sessionID string, src *cliSource) *genFile {
+	f := &genFile{}
+	b := &f.Builder
+	b.WriteString("package main\n\n")
+	required := []string{`"flag"`, `"fmt"`, `"os"`}
+	if needsStrconv(src.commands) {
+		required = append(required, `"strconv"`)
+	}
+	renderImports(b, required, src.imports)
+
+	renderDeclarations(f, src)
+
+	b.WriteString("func init() {\n")
+	for _, flag := range src.flags {
+		fmt.Fprintf(b, "\tflag.%s(&%s, %q, %s, %q)\n",
+			stdFlagFuncs[flag.Type], flag.Name, flagName(flag.Name), flag.Default, flag.usageText())
+	}
+	b.WriteString("\tflag.Usage = usage\n}\n\n")
+
+	renderStdUsage(b, name, sessionID, src.commands)
+
+	b.WriteString(`func main() {
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+`)
+
+	b.WriteString("// run executes the subcommand named by args[0], or the session code\nfunc run(args []string) error {\n")
+	if len(src.commands) > 0 {
+		b.WriteString("\tif len(args) > 0 {\n\t\tswitch args[0] {\n")
+		for _, command := range src.commands {
+			fmt.Fprintf(b, "\t\tcase %q:\n\t\t\treturn run%s(args[1:])\n", flagName(command.funcName), command.funcName)
+		}
+		b.WriteString("\t\t}\n\t\treturn fmt.Errorf(\"unknown command %q\", args[0])\n\t}\n\n")
+	}
+	if len(src.stmts) > 0 || len(src.commands) == 0 {
+		b.WriteString("\t// Session code\n")
+		for _, stmt := range src.stmts {
+			f.writeItem(stmt)
+			b.WriteString("\n")
+		}
+	} else {
+		b.WriteString("\tflag.Usage()\n")
+	}
+	b.WriteString("\treturn nil\n}\n\n")
+
+	for _, command := range src.commands {
+		renderStdCommand(b, command)
+	}
+
+	return f
+}
+
+// renderStdUsage renders the usage function listing flags and subcommands
+func renderStdUsage(b *strings.Builder, name, sessionID string, commands []cliCommand) {
+	b.WriteString("func usage() {\n\tout := flag.CommandLine.Output()\n")
+	fmt.Fprintf(b, "\tfmt.Fprintln(out, %q)\n", "Generated CLI from gosh session "+sessionID)
+	fmt.Fprintf(b, "\tfmt.Fprintln(out)\n")
+	if len(commands) > 0 {
+		fmt.Fprintf(b, "\tfmt.Fprintln(out, %q)\n", "Usage: "+name+" [flags] [command] [args]")
+		fmt.Fprintf(b, "\tfmt.Fprintln(out)\n\tfmt.Fprintln(out, \"Commands:\")\n")
+		for _, command := range commands {
+			fmt.Fprintf(b, "\tfmt.Fprintln(out, %q)\n", fmt.Sprintf("  %-20s %s", commandUse(command), command.short))
+		}
+	} else {
+		fmt.Fprintf(b, "\tfmt.Fprintln(out, %q)\n", "Usage: "+name+" [flags]")
+	}
+	b.WriteString("\tfmt.Fprintln(out)\n\tfmt.Fprintln(out, \"Flags:\")\n\tflag.PrintDefaults()\n}\n\n")
+}
+
+// renderStdCommand renders the function running a subcommand from its
+// positional arguments
+func renderStdCommand(b *strings.Builder, command cliCommand) {
+	fmt.Fprintf(b, "// run%s runs the %s subcommand\nfunc run%s(args []string) error {\n",
+		command.funcName, flagName(command.funcName), command.funcName)
+	fmt.Fprintf(b, "\tif len(args) != %d {\n\t\treturn fmt.Errorf(%q)\n\t}\n",
+		len(command.params), "usage: "+commandUse(command))
+	renderArgParsing(b, command.params)
+	call := fmt.Sprintf("%s(%s)", command.funcName, argList(command.params))
+	if command.returnsError {
+		fmt.Fprintf(b, "\treturn %s\n", call)
+	} else {
+		fmt.Fprintf(b, "\t%s\n\treturn nil\n", call)
+	}
+	b.WriteString("}\n\n")
+}
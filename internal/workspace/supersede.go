@@ -0,0 +1,254 @@
+package workspace
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// Markers around a declaration superseded by a later one in a session file.
+// The declaration stays in the file, commented out, so that resuming the
+// session replays the blocks exactly as they were evaluated.
+const (
+	supersededMarker    = "//gosh:superseded"
+	supersededEndMarker = "//gosh:end"
+)
+
+// declaredNames returns the package-level names declared by an item, with
+// methods named Type.Method, or nil when it is not a declaration
+func declaredNames(item sourceItem) []string {
+	if item.kind != declItem {
+		return nil
+	}
+
+	var names []string
+	add := func(ident *ast.Ident) {
+		if ident.Name != "_" {
+			names = append(names, ident.Name)
+		}
+	}
+	switch n := item.node.(type) {
+	case *ast.FuncDecl:
+		if n.Recv == nil || len(n.Recv.List) == 0 {
+			add(n.Name)
+		} else if recv := receiverType(n.Recv.List[0].Type); recv != "" {
+			names = append(names, recv+"."+n.Name.Name)
+		}
+	case *ast.GenDecl:
+		for _, spec := range n.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				add(s.Name)
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					add(name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// receiverType returns the name of the type of a method receiver
+func receiverType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(e.X)
+	case *ast.IndexExpr:
+		return receiverType(e.X)
+	case *ast.IndexListExpr:
+		return receiverType(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// supersededBy maps the index of each declaration of items whose names are
+// all declared again later to the index of the last of those declarations
+func supersededBy(items []sourceItem) map[int]int {
+	last := make(map[string]int)
+	for i, item := range items {
+		for _, name := range declaredNames(item) {
+			last[name] = i
+		}
+	}
+
+	superseded := make(map[int]int)
+	for i, item := range items {
+		names := declaredNames(item)
+		if len(names) == 0 {
+			continue
+		}
+		by := -1
+		for _, name := range names {
+			if last[name] == i {
+				by = -1
+				break
+			}
+			by = max(by, last[name])
+		}
+		if by >= 0 {
+			superseded[i] = by
+		}
+	}
+	return superseded
+}
+
+// latestDeclarations drops the declarations superseded by a later one, so
+// that generated code declares each name once, as last defined. Names
+// declared again later by a spec that also declares others are blanked, as
+// in var _, b = 1, 2.
+func latestDeclarations(decls []sourceItem) []sourceItem {
+	last := make(map[string]int)
+	for i, decl := range decls {
+		for _, name := range declaredNames(decl) {
+			last[name] = i
+		}
+	}
+
+	superseded := supersededBy(decls)
+	kept := make([]sourceItem, 0, len(decls)-len(superseded))
+	for i, decl := range decls {
+		if _, ok := superseded[i]; ok {
+			continue
+		}
+		stale := make(map[string]bool)
+		for _, name := range declaredNames(decl) {
+			if last[name] != i {
+				stale[name] = true
+			}
+		}
+		if len(stale) > 0 {
+			decl.text = blankNames(decl, stale)
+		}
+		kept = append(kept, decl)
+	}
+	return kept
+}
+
+// blankNames replaces the given names declared by a type, const or var
+// declaration with the blank identifier
+func blankNames(item sourceItem, names map[string]bool) string {
+	gen, ok := item.node.(*ast.GenDecl)
+	if !ok {
+		return item.text
+	}
+
+	var idents []*ast.Ident
+	for _, spec := range gen.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			idents = append(idents, s.Name)
+		case *ast.ValueSpec:
+			idents = append(idents, s.Names...)
+		}
+	}
+
+	// Replace from the end so the offsets of earlier names stay valid
+	text := item.text
+	for i := len(idents) - 1; i >= 0; i-- {
+		ident := idents[i]
+		start := int(ident.Pos() - item.pos)
+		end := start + len(ident.Name)
+		if !names[ident.Name] || start < 0 || end > len(text) || text[start:end] != ident.Name {
+			continue
+		}
+		text = text[:start] + "_" + text[end:]
+	}
+	return text
+}
+
+// supersededCode returns the code of each block with the declarations
+// superseded by a later one commented out between markers
+func supersededCode(blocks []codeBlock) []string {
+	var items []sourceItem
+	for i, block := range blocks {
+		blockItems, err := parseBlock(block.code)
+		if err != nil {
+			continue
+		}
+		for _, item := range blockItems {
+			item.block = i
+			items = append(items, item)
+		}
+	}
+
+	// Comment out the declarations of each block from the last one, so the
+	// lines of the previous ones do not move
+	code := make([]string, len(blocks))
+	lines := make([][]string, len(blocks))
+	for i, block := range blocks {
+		code[i] = block.code
+	}
+	superseded := supersededBy(items)
+	for i := len(items) - 1; i >= 0; i-- {
+		by, ok := superseded[i]
+		if !ok {
+			continue
+		}
+		item := items[i]
+		if lines[item.block] == nil {
+			lines[item.block] = strings.Split(code[item.block], "\n")
+		}
+		lines[item.block] = commentOut(lines[item.block], item, items[by].block+1)
+	}
+	for i := range blocks {
+		if lines[i] != nil {
+			code[i] = strings.Join(lines[i], "\n")
+		}
+	}
+	return code
+}
+
+// commentOut comments out the lines of an item superseded by the given block
+func commentOut(lines []string, item sourceItem, by int) []string {
+	start := item.line - 1
+	end := start + strings.Count(item.text, "\n") + 1
+	if start < 0 || end > len(lines) {
+		return lines
+	}
+
+	commented := []string{fmt.Sprintf("%s by block %d", supersededMarker, by)}
+	for _, line := range lines[start:end] {
+		if line == "" {
+			commented = append(commented, "//")
+		} else {
+			commented = append(commented, "// "+line)
+		}
+	}
+	commented = append(commented, supersededEndMarker)
+
+	result := append([]string{}, lines[:start]...)
+	result = append(result, commented...)
+	return append(result, lines[end:]...)
+}
+
+// restoreSuperseded uncomments the superseded declarations of a block read
+// from a session file
+func restoreSuperseded(code string) string {
+	if !strings.Contains(code, supersededMarker) {
+		return code
+	}
+
+	lines := strings.Split(code, "\n")
+	restored := make([]string, 0, len(lines))
+	inside := false
+	for _, line := range lines {
+		switch {
+		case !inside && strings.HasPrefix(line, supersededMarker):
+			inside = true
+		case inside && line == supersededEndMarker:
+			inside = false
+		case inside:
+			if uncommented, ok := strings.CutPrefix(line, "// "); ok {
+				restored = append(restored, uncommented)
+			} else {
+				restored = append(restored, strings.TrimPrefix(line, "//"))
+			}
+		default:
+			restored = append(restored, line)
+		}
+	}
+	return strings.Join(restored, "\n")
+}
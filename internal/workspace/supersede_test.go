@@ -0,0 +1,106 @@
+package workspace
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDeclaredNames(t *testing.T) {
+	code := "import \"strings\"\n\nfunc parse() {}\n\nfunc (p *Parser[T]) Parse() {}\n\ntype Parser[T any] struct{}\n\nvar a, _, b = 1, 2, 3\n\nx := 1"
+	items, err := parseBlock(code)
+	if err != nil {
+		t.Fatalf("Failed to parse block: %v", err)
+	}
+
+	var names [][]string
+	for _, item := range items {
+		names = append(names, declaredNames(item))
+	}
+	want := [][]string{nil, {"parse"}, {"Parser.Parse"}, {"Parser"}, {"a", "b"}, nil}
+	if len(names) != len(want) {
+		t.Fatalf("Expected %d items, got %q", len(want), names)
+	}
+	for i := range want {
+		if !slices.Equal(names[i], want[i]) {
+			t.Errorf("Item %d: expected %q, got %q", i, want[i], names[i])
+		}
+	}
+}
+
+func TestSupersededSession(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	blocks := []string{
+		"// parse doubles\nfunc parse(s string) int {\n\n\treturn len(s) * 2\n}\n\nvar a, b = 1, 2",
+		"n := parse(\"ab\")\nfmt.Println(n)",
+		"func parse(s string) int { return len(s) }\n\nvar a = 3",
+		"func Parse(s string) int { return parse(s) }",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(ws.sessionFile(ws.SessionID()))
+	if err != nil {
+		t.Fatalf("Failed to read session file: %v", err)
+	}
+	session := string(content)
+	if strings.Count(session, "\nfunc parse(") != 1 {
+		t.Errorf("Expected a single active parse declaration:\n%s", session)
+	}
+	if !strings.Contains(session, "//gosh:superseded by block 3\n// // parse doubles\n// func parse(s string) int {\n//\n") {
+		t.Errorf("Expected the first parse to be commented out:\n%s", session)
+	}
+	if !strings.Contains(session, "\nvar a, b = 1, 2") {
+		t.Errorf("Partially redeclared variables should be kept:\n%s", session)
+	}
+
+	// Resuming replays the blocks as they were evaluated
+	loaded, err := ws.LoadSession(ws.SessionID())
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	if !slices.Equal(loaded, blocks) {
+		t.Errorf("Expected the full trail of blocks, got %q", loaded)
+	}
+	if !slices.Equal(ws.GetCodeBlocks(), blocks) {
+		t.Errorf("The workspace should keep the full trail, got %q", ws.GetCodeBlocks())
+	}
+
+	src := ws.parseSession()
+	var decls []string
+	for _, decl := range src.decls {
+		decls = append(decls, decl.text)
+	}
+	want := []string{"var _, b = 1, 2", "func parse(s string) int { return len(s) }", "var a = 3", "func Parse(s string) int { return parse(s) }"}
+	if !slices.Equal(decls, want) {
+		t.Errorf("Expected only the latest declarations, got %q", decls)
+	}
+
+	// The exported package declares every name once
+	dir, err := ws.GeneratePackage("parsers", t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to generate package: %v", err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(dir, "parsers.go"), nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse package: %v", err)
+	}
+	if _, err := new(types.Config).Check("parsers", fset, []*ast.File{file}, nil); err != nil {
+		t.Errorf("Exported package does not type-check: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// genFile is generated Go source that remembers which session block each of
+// its lines comes from, so compile errors can be reported against the session
+type genFile struct {
+	strings.Builder
+	origins []lineOrigin
+}
+
+// lineOrigin maps lines of a generated file to the session item they hold
+type lineOrigin struct {
+	start, end int // first and last line in the generated file, 1-based
+	item       sourceItem
+}
+
+// writeItem writes the source of a session item, which must start on a new line
+func (f *genFile) writeItem(item sourceItem) {
+	start := strings.Count(f.String(), "\n") + 1
+	f.WriteString(item.text)
+	f.origins = append(f.origins, lineOrigin{
+		start: start,
+		end:   start + strings.Count(item.text, "\n"),
+		item:  item,
+	})
+}
+
+// Diagnostic is a compile error found in generated code
+type Diagnostic struct {
+	Block   int // 1-based session block holding the error, 0 for generated code
+	Line    int // line in the session block
+	Message string
+	// Warning is set when the code could not be fully checked rather than
+	// found wrong, such as when an imported package cannot be resolved
+	Warning bool
+}
+
+// String formats the diagnostic with its session location
+func (d Diagnostic) String() string {
+	msg := d.Message
+	if d.Warning {
+		msg = "warning: " + msg
+	}
+	if d.Block == 0 {
+		return "generated code: " + msg
+	}
+	return fmt.Sprintf("block %d, line %d: %s", d.Block, d.Line, msg)
+}
+
+// CompileError reports that a generated file does not compile, or, when all
+// its diagnostics are warnings, that it could only be partly type-checked
+type CompileError struct {
+	File        string
+	Diagnostics []Diagnostic
+}
+
+// Partial reports whether the file was only partly checked, without errors
+func (e *CompileError) Partial() bool {
+	for _, d := range e.Diagnostics {
+		if !d.Warning {
+			return false
+		}
+	}
+	return true
+}
+
+// Error lists the diagnostics of the generated file
+func (e *CompileError) Error() string {
+	var b strings.Builder
+	if e.Partial() {
+		fmt.Fprintf(&b, "%s could only be partly type-checked:", e.File)
+	} else {
+		fmt.Fprintf(&b, "%s does not compile:", e.File)
+	}
+	for _, d := range e.Diagnostics {
+		b.WriteString("\n  ")
+		b.WriteString(d.String())
+	}
+	return b.String()
+}
+
+// check type-checks the generated file as if it were in dir and returns its
+// compile errors mapped back to the session. Imports are resolved like go
+// build does in dir, so from the module of the generated tool. A package that
+// cannot be imported, such as a module missing from the module cache, is
+// reported as a warning: the type checker skips every use of it.
+func (f *genFile) check(dir string) []Diagnostic {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(dir, "main.go"), f.String(), parser.AllErrors)
+	if err != nil {
+		var list scanner.ErrorList
+		if errors.As(err, &list) {
+			diags := make([]Diagnostic, 0, len(list))
+			for _, e := range list {
+				diags = append(diags, f.diagnostic(e.Pos.Line, e.Msg))
+			}
+			return diags
+		}
+		return []Diagnostic{{Message: err.Error()}}
+	}
+
+	imports := make([]string, 0, len(file.Imports))
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, path)
+		}
+	}
+
+	var diags []Diagnostic
+	conf := types.Config{
+		Importer: dirImporter(fset, dir, imports),
+		Error: func(err error) {
+			var typeErr types.Error
+			if !errors.As(err, &typeErr) {
+				diags = append(diags, Diagnostic{Message: err.Error()})
+				return
+			}
+			diag := f.diagnostic(fset.Position(typeErr.Pos).Line, typeErr.Msg)
+			if strings.HasPrefix(typeErr.Msg, "could not import") {
+				// Keep the package path, not the lengthy reason
+				msg, _, _ := strings.Cut(typeErr.Msg, " (")
+				diag.Message = msg + ": its uses were not type-checked"
+				diag.Warning = true
+			}
+			diags = append(diags, diag)
+		},
+	}
+	conf.Check("main", fset, []*ast.File{file}, nil)
+	return diags
+}
+
+// dirImporter returns an importer resolving the given imports like go build
+// does in dir. It reads the export data go list builds in dir, since the
+// source importer runs go list in the current directory, which would resolve
+// imports from, and add requirements to, whatever module that is in. The
+// source importer is only used when go list cannot run.
+func dirImporter(fset *token.FileSet, dir string, imports []string) types.Importer {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return importer.ForCompiler(fset, "source", nil)
+	}
+
+	args := append([]string{"list", "-e", "-export", "-deps", "-f", "{{if .Export}}{{.ImportPath}}={{.Export}}{{end}}", "--"}, imports...)
+	cmd := exec.Command(goBin, args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return importer.ForCompiler(fset, "source", nil)
+	}
+
+	exports := make(map[string]string)
+	for _, line := range strings.Split(string(output), "\n") {
+		if path, export, ok := strings.Cut(line, "="); ok {
+			exports[path] = export
+		}
+	}
+	return importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		export, ok := exports[path]
+		if !ok {
+			return nil, fmt.Errorf("package %s not found in %s", path, dir)
+		}
+		return os.Open(export)
+	})
+}
+
+// diagnostic locates a line of the generated file in the session
+func (f *genFile) diagnostic(line int, msg string) Diagnostic {
+	for _, origin := range f.origins {
+		if line >= origin.start && line <= origin.end {
+			return Diagnostic{
+				Block:   origin.item.block + 1,
+				Line:    origin.item.line + line - origin.start,
+				Message: msg,
+			}
+		}
+	}
+	return Diagnostic{Message: msg}
+}
+
+// buildTool runs go build in dir without keeping the binary. It returns nil
+// without building when no Go toolchain is installed.
+func buildTool(dir string) error {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return nil
+	}
+
+	cmd := exec.Command(goBin, "build", "-o", os.DevNull, ".")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("go build failed: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
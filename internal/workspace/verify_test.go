@@ -0,0 +1,149 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateCLICompileError(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	blocks := []string{
+		"name := \"gosh\"\nfmt.Println(name)",
+		"func greet() string {\n\treturn 42\n}",
+		"fmt.Println(undefinedValue)",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	for _, flavor := range []Flavor{CobraFlavor, StdFlavor} {
+		err := ws.GenerateCLI("broken-"+string(flavor), CLIOptions{Flavor: flavor})
+
+		var compileErr *CompileError
+		if !errors.As(err, &compileErr) {
+			t.Fatalf("%s: expected a compile error, got %v", flavor, err)
+		}
+
+		want := []Diagnostic{
+			{Block: 2, Line: 2},
+			{Block: 3, Line: 1},
+		}
+		if len(compileErr.Diagnostics) != len(want) {
+			t.Fatalf("%s: expected %d diagnostics, got %v", flavor, len(want), compileErr.Diagnostics)
+		}
+		for i, d := range compileErr.Diagnostics {
+			if d.Block != want[i].Block || d.Line != want[i].Line {
+				t.Errorf("%s: diagnostic %d: expected block %d, line %d, got %s",
+					flavor, i, want[i].Block, want[i].Line, d)
+			}
+		}
+		if !strings.Contains(compileErr.Diagnostics[1].Message, "undefinedValue") {
+			t.Errorf("%s: expected undefinedValue in message, got %q", flavor, compileErr.Diagnostics[1].Message)
+		}
+	}
+}
+
+func TestGenerateCLIUnresolvedImport(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+	t.Setenv("GOPROXY", "off")
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	blocks := []string{
+		"import \"example.com/missing/greet\"",
+		"fmt.Println(greet.Hello())",
+	}
+	for _, block := range blocks {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	err = ws.GenerateCLI("unresolved", CLIOptions{Flavor: StdFlavor})
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) {
+		t.Fatalf("Expected the partial check to be reported, got %v", err)
+	}
+	if !compileErr.Partial() || len(compileErr.Diagnostics) != 1 {
+		t.Fatalf("Expected a single warning, got %v", compileErr.Diagnostics)
+	}
+	if d := compileErr.Diagnostics[0]; !strings.Contains(d.Message, "example.com/missing/greet") || !strings.Contains(d.String(), "warning: ") {
+		t.Errorf("Expected a warning naming the package, got %s", d)
+	}
+	if !strings.Contains(err.Error(), "partly type-checked") {
+		t.Errorf("Unexpected error message %q", err)
+	}
+}
+
+func TestGenerateCLIKeepsCurrentModule(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	// The tests run in this package, inside the gosh module
+	goMod, err := os.ReadFile("../../go.mod")
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if err := ws.AddCodeBlock("fmt.Println(\"cobra\")"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if err := ws.GenerateCLI("cobra-tool", CLIOptions{Flavor: CobraFlavor}); err != nil {
+		t.Fatalf("Failed to generate CLI: %v", err)
+	}
+
+	after, err := os.ReadFile("../../go.mod")
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if string(after) != string(goMod) {
+		t.Errorf("Expected type-checking the tool to leave the current module alone, go.mod became:\n%s", after)
+	}
+}
+
+func TestGenerateCLIBuild(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	if err := ws.AddCodeBlock("fmt.Println(\"built\")"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	if err := ws.GenerateCLI("built", CLIOptions{Flavor: StdFlavor, Build: true}); err != nil {
+		t.Fatalf("Expected the generated CLI to build, got %v", err)
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	tests := []struct {
+		diag Diagnostic
+		want string
+	}{
+		{Diagnostic{Block: 3, Line: 2, Message: "undefined: x"}, "block 3, line 2: undefined: x"},
+		{Diagnostic{Message: "missing return"}, "generated code: missing return"},
+	}
+	for _, tt := range tests {
+		if got := tt.diag.String(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package workspace
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
 	"time"
 )
 
@@ -18,18 +20,86 @@ type Workspace struct {
 	rootPath    string
 	internalPath string
 	sessionID   string
-	codeBlocks  []string
+	codeBlocks  []codeBlock
+	meta        SessionMeta
+	imports     []string // packages imported into the interpreter before the session
+
+	unlockSession func() // releases the lock of the current session
+}
+
+// codeBlock is a block of session code that evaluated successfully
+type codeBlock struct {
+	code   string
+	output string // standard output produced when the block was evaluated
 }
 
-// New creates a new workspace in the user's home directory
+// homeEnv overrides the location of the global workspace
+const homeEnv = "GOSH_HOME"
+
+// New creates a new workspace at the default location, see DefaultPath
 func New() (*Workspace, error) {
+	workspaceDir, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return NewAt(workspaceDir)
+}
+
+// DefaultPath returns the workspace used when none is given explicitly:
+// $GOSH_HOME when set, otherwise the project workspace of the current
+// directory when there is one, otherwise ~/.gosh
+func DefaultPath() (string, error) {
+	if dir := os.Getenv(homeEnv); dir != "" {
+		return dir, nil
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	globalDir := filepath.Join(homeDir, defaultWorkspaceDir)
+
+	if cwd, err := os.Getwd(); err == nil {
+		if dir, ok := FindProjectPath(cwd); ok && dir != globalDir {
+			return dir, nil
+		}
+	}
+	return globalDir, nil
+}
+
+// ProjectPath returns the project workspace of the given directory
+func ProjectPath(dir string) string {
+	return filepath.Join(dir, defaultWorkspaceDir)
+}
+
+// FindProjectPath looks for a project workspace, a .gosh directory, in dir
+// and its parents
+func FindProjectPath(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		candidate := ProjectPath(dir)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// NewAt creates a new workspace rooted at the given directory, creating it
+// when needed
+func NewAt(root string) (*Workspace, error) {
+	workspaceDir, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace directory: %w", err)
 	}
 
-	workspaceDir := filepath.Join(homeDir, defaultWorkspaceDir)
-	
 	// Create workspace directory if it doesn't exist
 	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create workspace directory: %w", err)
@@ -42,23 +112,115 @@ func New() (*Workspace, error) {
 	}
 
 	// Initialize go.mod if it doesn't exist
-	goModPath := filepath.Join(workspaceDir, "go.mod")
-	if _, err := os.Stat(goModPath); os.IsNotExist(err) {
-		modContent := "module gosh\n\ngo 1.25\n"
-		if err := os.WriteFile(goModPath, []byte(modContent), 0644); err != nil {
-			return nil, fmt.Errorf("failed to create go.mod: %w", err)
-		}
+	if err := initModule(workspaceDir); err != nil {
+		return nil, err
 	}
 
 	// Create session ID
-	sessionID := time.Now().Format("20060102_150405")
+	sessionID, err := newSessionID(internalPath)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Workspace{
+	w := &Workspace{
 		rootPath:    workspaceDir,
 		internalPath: internalPath,
 		sessionID:   sessionID,
-		codeBlocks:  make([]string, 0),
-	}, nil
+		codeBlocks:  make([]codeBlock, 0),
+	}
+	if w.unlockSession, err = w.lockSession(sessionID); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// initModule writes the workspace go.mod unless another gosh instance already did
+func initModule(workspaceDir string) error {
+	unlock, err := lockPath(filepath.Join(workspaceDir, lockFileName))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	goModPath := filepath.Join(workspaceDir, "go.mod")
+	if _, err := os.Stat(goModPath); os.IsNotExist(err) {
+		return writeModuleFile(workspaceDir, WorkspaceModule)
+	}
+	return nil
+}
+
+// newSessionID returns an unused session ID made of the current time, which
+// keeps IDs sortable, and a random suffix, which keeps gosh instances started
+// in the same second from sharing a session file. The ID is reserved by
+// creating its session file, empty until a block is saved.
+func newSessionID(internalPath string) (string, error) {
+	suffix := make([]byte, 3)
+	for range 10 {
+		if _, err := rand.Read(suffix); err != nil {
+			return "", fmt.Errorf("failed to generate session ID: %w", err)
+		}
+		id := time.Now().Format("20060102_150405") + "_" + hex.EncodeToString(suffix)
+
+		// Metadata left without a session file still belongs to another session
+		if _, err := os.Stat(filepath.Join(internalPath, sessionFilePrefix+id+sessionMetaSuffix)); !os.IsNotExist(err) {
+			continue
+		}
+		file, err := os.OpenFile(filepath.Join(internalPath, sessionFilePrefix+id+sessionFileSuffix), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to reserve session file: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return "", fmt.Errorf("failed to reserve session file: %w", err)
+		}
+		return id, nil
+	}
+	return "", fmt.Errorf("failed to generate an unused session ID")
+}
+
+// releaseSessionID removes the session file reserving id, along with the
+// session metadata and lock file, unless a block was saved to it
+func (w *Workspace) releaseSessionID(id string) error {
+	path := w.sessionFile(id)
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to release session file: %w", err)
+	}
+	if err := os.Remove(w.metaFile(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session metadata: %w", err)
+	}
+	if err := os.Remove(w.sessionLockFile(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session lock file: %w", err)
+	}
+	return nil
+}
+
+// switchSession makes id the current session, whose lock is released by
+// unlock, and releases the previous session
+func (w *Workspace) switchSession(id string, unlock func()) error {
+	previous, previousUnlock := w.sessionID, w.unlockSession
+	w.sessionID, w.unlockSession = id, unlock
+	err := w.releaseSessionID(previous)
+	if previousUnlock != nil {
+		previousUnlock()
+	}
+	return err
+}
+
+// Close releases the ID of the current session when no block was saved,
+// removing its metadata, and the lock keeping other gosh instances from
+// using the session
+func (w *Workspace) Close() error {
+	err := w.releaseSessionID(w.sessionID)
+	if w.unlockSession != nil {
+		w.unlockSession()
+		w.unlockSession = nil
+	}
+	return err
 }
 
 // Path returns the workspace root directory path
@@ -76,20 +238,24 @@ func (w *Workspace) SessionID() string {
 	return w.sessionID
 }
 
+// SetImports sets the packages the interpreter imports before evaluating
+// the session, which exported code imports when it uses them
+func (w *Workspace) SetImports(paths []string) {
+	w.imports = slices.Clone(paths)
+}
+
 // AddCodeBlock adds a compiled code block to the workspace
 func (w *Workspace) AddCodeBlock(code string) error {
-	w.codeBlocks = append(w.codeBlocks, code)
+	return w.AddCodeBlockWithOutput(code, "")
+}
+
+// AddCodeBlockWithOutput adds a compiled code block to the workspace along
+// with the standard output its evaluation produced
+func (w *Workspace) AddCodeBlockWithOutput(code, output string) error {
+	w.codeBlocks = append(w.codeBlocks, codeBlock{code: code, output: output})
 	
 	// Save to session file in internal/
-	sessionFile := filepath.Join(w.internalPath, fmt.Sprintf("session_%s.go", w.sessionID))
-	
-	// Build file content
-	content := "package internal\n\nimport (\n\t\"fmt\"\n)\n\n"
-	for _, block := range w.codeBlocks {
-		content += "// Block\n" + block + "\n\n"
-	}
-	
-	if err := os.WriteFile(sessionFile, []byte(content), 0644); err != nil {
+	if err := writeFileAtomic(w.sessionFile(w.sessionID), []byte(renderSession(w.codeBlocks)), 0644); err != nil {
 		return fmt.Errorf("failed to write session file: %w", err)
 	}
 	
@@ -98,81 +264,52 @@ func (w *Workspace) AddCodeBlock(code string) error {
 
 // GetCodeBlocks returns all code blocks from the current session
 func (w *Workspace) GetCodeBlocks() []string {
-	return w.codeBlocks
+	blocks := make([]string, len(w.codeBlocks))
+	for i, block := range w.codeBlocks {
+		blocks[i] = block.code
+	}
+	return blocks
 }
 
-// Clear clears all code blocks
+// GetBlockOutputs returns the standard output recorded for each code block
+func (w *Workspace) GetBlockOutputs() []string {
+	outputs := make([]string, len(w.codeBlocks))
+	for i, block := range w.codeBlocks {
+		outputs[i] = block.output
+	}
+	return outputs
+}
+
+// Clear clears all code blocks, removing the session file and metadata
 func (w *Workspace) Clear() error {
-	w.codeBlocks = make([]string, 0)
+	w.codeBlocks = make([]codeBlock, 0)
+	w.meta = SessionMeta{}
 	
 	// Remove session file
-	sessionFile := filepath.Join(w.internalPath, fmt.Sprintf("session_%s.go", w.sessionID))
-	if err := os.Remove(sessionFile); err != nil && !os.IsNotExist(err) {
+	if err := os.Remove(w.sessionFile(w.sessionID)); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove session file: %w", err)
 	}
+	if err := os.Remove(w.metaFile(w.sessionID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session metadata: %w", err)
+	}
 	
 	return nil
 }
 
-// GenerateCobraCLI generates a Cobra-based CLI tool from the session code
-func (w *Workspace) GenerateCobraCLI(name string) error {
-	if name == "" {
-		return fmt.Errorf("CLI name cannot be empty")
-	}
-	
-	// Create CLI directory
-	cliDir := filepath.Join(w.rootPath, "cmd", name)
-	if err := os.MkdirAll(cliDir, 0755); err != nil {
-		return fmt.Errorf("failed to create CLI directory: %w", err)
+// SetCodeBlocks replaces the code blocks of the session, along with the
+// standard output each produced, and rewrites the session file
+func (w *Workspace) SetCodeBlocks(blocks, outputs []string) error {
+	if len(outputs) != len(blocks) {
+		return fmt.Errorf("expected %d block outputs, got %d", len(blocks), len(outputs))
 	}
-	
-	// Generate main.go with Cobra
-	mainContent := fmt.Sprintf(`package main
-
-import (
-	"fmt"
-	"os"
-
-	"github.com/spf13/cobra"
-)
-
-var rootCmd = &cobra.Command{
-	Use:   "%s",
-	Short: "Generated CLI from gosh session %s",
-	Run: func(cmd *cobra.Command, args []string) {
-		// Session code
-%s
-	},
-}
 
-func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
-}
-`, name, w.sessionID, w.formatCodeBlocksForCLI())
-	
-	mainPath := filepath.Join(cliDir, "main.go")
-	if err := os.WriteFile(mainPath, []byte(mainContent), 0644); err != nil {
-		return fmt.Errorf("failed to write main.go: %w", err)
+	codeBlocks := make([]codeBlock, len(blocks))
+	for i, code := range blocks {
+		codeBlocks[i] = codeBlock{code: code, output: outputs[i]}
 	}
-	
-	return nil
-}
-
-// formatCodeBlocksForCLI formats code blocks for inclusion in CLI tool
-func (w *Workspace) formatCodeBlocksForCLI() string {
-	var result strings.Builder
-	for _, block := range w.codeBlocks {
-		lines := strings.Split(block, "\n")
-		for _, line := range lines {
-			if line != "" {
-				result.WriteString("\t\t")
-				result.WriteString(line)
-				result.WriteString("\n")
-			}
-		}
+	if err := writeFileAtomic(w.sessionFile(w.sessionID), []byte(renderSession(codeBlocks)), 0644); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
 	}
-	return result.String()
+	w.codeBlocks = codeBlocks
+	return w.pruneCheckpoints()
 }
@@ -5,9 +5,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	ws, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create workspace: %v", err)
@@ -38,6 +41,8 @@ func TestNew(t *testing.T) {
 }
 
 func TestAddCodeBlock(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	ws, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create workspace: %v", err)
@@ -66,6 +71,8 @@ func TestAddCodeBlock(t *testing.T) {
 }
 
 func TestGetCodeBlocks(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	ws, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create workspace: %v", err)
@@ -98,6 +105,8 @@ func TestGetCodeBlocks(t *testing.T) {
 }
 
 func TestClear(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	ws, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create workspace: %v", err)
@@ -126,7 +135,42 @@ func TestClear(t *testing.T) {
 	}
 }
 
+func TestSetCodeBlocks(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	for _, block := range []string{"x := 1", "y := 2", "z := 3"} {
+		if err := ws.AddCodeBlock(block); err != nil {
+			t.Fatalf("Failed to add code block: %v", err)
+		}
+	}
+
+	if err := ws.SetCodeBlocks([]string{"x := 1", "z := 3"}, []string{"", "3\n"}); err != nil {
+		t.Fatalf("Failed to set code blocks: %v", err)
+	}
+	if blocks := ws.GetCodeBlocks(); len(blocks) != 2 || blocks[1] != "z := 3" {
+		t.Errorf("Unexpected blocks %q", blocks)
+	}
+	if outputs := ws.GetBlockOutputs(); outputs[1] != "3\n" {
+		t.Errorf("Unexpected outputs %q", outputs)
+	}
+
+	saved, err := ws.LoadSession(ws.SessionID())
+	if err != nil || len(saved) != 2 || saved[1] != "z := 3" {
+		t.Errorf("Session file should be rewritten, got %q (%v)", saved, err)
+	}
+
+	if err := ws.SetCodeBlocks([]string{"x := 1"}, nil); err == nil {
+		t.Error("Expected an error for missing outputs")
+	}
+}
+
 func TestGenerateCobraCLI(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	ws, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create workspace: %v", err)
@@ -175,7 +219,80 @@ func TestGenerateCobraCLI(t *testing.T) {
 	}
 }
 
+func TestGenerateCobraCLIRequirements(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	if err := ws.AddCodeBlock(`fmt.Println("deps")`); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	// Generating twice must not duplicate the require directive
+	for i := 0; i < 2; i++ {
+		if err := ws.GenerateCobraCLI("test_deps_cli"); err != nil {
+			t.Fatalf("Failed to generate CLI: %v", err)
+		}
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(ws.Path(), "go.mod"))
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if count := strings.Count(string(goMod), "github.com/spf13/cobra v"); count != 1 {
+		t.Errorf("go.mod should require cobra exactly once, found %d times", count)
+	}
+
+	goSum, err := os.ReadFile(filepath.Join(ws.Path(), "go.sum"))
+	if err != nil {
+		t.Fatalf("Failed to read go.sum: %v", err)
+	}
+	if !strings.Contains(string(goSum), "github.com/spf13/cobra v1.10.1 h1:") {
+		t.Error("go.sum should contain the cobra checksum")
+	}
+}
+
+func TestGenerateCLIStandalone(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+
+	if err := ws.AddCodeBlock(`fmt.Println("standalone")`); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+
+	cliName := "test_standalone_cli"
+	if err := ws.GenerateCLI(cliName, CLIOptions{Standalone: true}); err != nil {
+		t.Fatalf("Failed to generate CLI: %v", err)
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(ws.CLIPath(cliName), "go.mod"))
+	if err != nil {
+		t.Fatalf("Standalone go.mod not written: %v", err)
+	}
+
+	content := string(goMod)
+	if !strings.HasPrefix(content, "module "+cliName+"\n") {
+		t.Errorf("go.mod should declare module %s, got:\n%s", cliName, content)
+	}
+	if !strings.Contains(content, "github.com/spf13/cobra v1.10.1") {
+		t.Error("Standalone go.mod should require cobra")
+	}
+
+	if _, err := os.Stat(filepath.Join(ws.CLIPath(cliName), "go.sum")); err != nil {
+		t.Errorf("Standalone go.sum not written: %v", err)
+	}
+}
+
 func TestPath(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	ws, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create workspace: %v", err)
@@ -193,6 +310,8 @@ func TestPath(t *testing.T) {
 }
 
 func TestInternalPath(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	ws, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create workspace: %v", err)
@@ -215,6 +334,8 @@ func TestInternalPath(t *testing.T) {
 }
 
 func TestSessionID(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
 	ws, err := New()
 	if err != nil {
 		t.Fatalf("Failed to create workspace: %v", err)
@@ -225,8 +346,125 @@ func TestSessionID(t *testing.T) {
 		t.Error("SessionID() returned empty string")
 	}
 
-	// Verify session ID format (YYYYMMDD_HHMMSS)
-	if len(sessionID) != 15 {
-		t.Errorf("Session ID should be 15 characters long, got %d", len(sessionID))
+	// Verify session ID format (YYYYMMDD_HHMMSS_xxxxxx)
+	if len(sessionID) != 22 {
+		t.Errorf("Session ID should be 22 characters long, got %d", len(sessionID))
+	}
+	if _, err := time.Parse("20060102_150405", sessionID[:15]); err != nil {
+		t.Errorf("Session ID should start with a timestamp: %v", err)
+	}
+
+	// Workspaces created in the same second get different sessions
+	other, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if other.SessionID() == sessionID {
+		t.Errorf("Session IDs should be unique, got %s twice", sessionID)
+	}
+}
+
+func TestSessionIDReservation(t *testing.T) {
+	t.Setenv("GOSH_HOME", t.TempDir())
+
+	saved, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if err := saved.AddCodeBlock("x := 1"); err != nil {
+		t.Fatalf("Failed to add code block: %v", err)
+	}
+	if err := saved.Close(); err != nil {
+		t.Fatalf("Failed to close workspace: %v", err)
+	}
+
+	ws, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	info, err := os.Stat(ws.sessionFile(ws.SessionID()))
+	if err != nil || info.Size() != 0 {
+		t.Fatalf("Expected an empty session file reserving the ID, got %v, %v", info, err)
+	}
+
+	// Reserved sessions are neither listed nor resumed
+	other, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if latest, err := other.LatestSessionID(); err != nil || latest != saved.SessionID() {
+		t.Errorf("Expected to resume %s, got %q, %v", saved.SessionID(), latest, err)
+	}
+	sessions, err := other.Sessions()
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != saved.SessionID() {
+		t.Errorf("Expected only the saved session, got %v", sessions)
+	}
+
+	// Closing releases the ID unless a block was saved
+	if err := ws.Close(); err != nil {
+		t.Fatalf("Failed to close workspace: %v", err)
+	}
+	if _, err := os.Stat(ws.sessionFile(ws.SessionID())); !os.IsNotExist(err) {
+		t.Error("Closing should remove the empty session file")
+	}
+	if _, err := os.Stat(saved.sessionFile(saved.SessionID())); err != nil {
+		t.Errorf("Closing should keep the saved session: %v", err)
+	}
+}
+
+func TestNewAt(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "project", ".gosh")
+
+	ws, err := NewAt(root)
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if ws.Path() != root {
+		t.Errorf("Expected workspace at %s, got %s", root, ws.Path())
+	}
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); err != nil {
+		t.Errorf("go.mod should be created: %v", err)
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	project := t.TempDir()
+	nested := filepath.Join(project, "internal", "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	t.Chdir(nested)
+
+	// Without a project workspace, the global one is used
+	t.Setenv("GOSH_HOME", "")
+	if got, err := DefaultPath(); err != nil || got != filepath.Join(home, ".gosh") {
+		t.Errorf("Expected the global workspace, got %s (%v)", got, err)
+	}
+
+	// A .gosh directory in a parent makes a project workspace
+	if err := os.Mkdir(ProjectPath(project), 0755); err != nil {
+		t.Fatalf("Failed to create project workspace: %v", err)
+	}
+	want, _ := filepath.EvalSymlinks(ProjectPath(project))
+	got, err := DefaultPath()
+	if err != nil {
+		t.Fatalf("Failed to get default path: %v", err)
+	}
+	if got, _ = filepath.EvalSymlinks(got); got != want {
+		t.Errorf("Expected the project workspace %s, got %s", want, got)
+	}
+
+	// GOSH_HOME overrides both
+	override := t.TempDir()
+	t.Setenv("GOSH_HOME", override)
+	if got, err := DefaultPath(); err != nil || got != override {
+		t.Errorf("Expected GOSH_HOME %s, got %s (%v)", override, got, err)
 	}
 }